@@ -36,6 +36,13 @@ func newItem(item interface{}) *Item {
 }
 
 func (c *Cache) Add(id string, item interface{}) {
+	c.Lock()
+	c.add(id, item)
+	c.Unlock()
+}
+
+// add stores an item, the lock being held by the caller
+func (c *Cache) add(id string, item interface{}) {
 	insertable := newItem(item)
 	c.holds[id] = insertable
 }
@@ -51,7 +58,7 @@ func (c *Cache) Update(id string, ele interface{}) {
 	defer c.Unlock()
 
 	if _, ok := c.holds[id]; !ok {
-		c.Add(id, ele)
+		c.add(id, ele)
 	} else {
 		c.holds[id] = &Item{
 			lastUsed: time.Now().UnixNano(),
@@ -60,21 +67,39 @@ func (c *Cache) Update(id string, ele interface{}) {
 	}
 }
 
-func (c *Cache) Size() int {
-	var i int
-	for range c.holds {
-		i++
+// Get returns the item stored with the given ID
+func (c *Cache) Get(id string) (interface{}, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	item, ok := c.holds[id]
+	if !ok {
+		return nil, false
 	}
+	item.lastUsed = time.Now().UnixNano()
 
-	return i
+	return item.item, true
+}
+
+func (c *Cache) Size() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return len(c.holds)
 }
 
 func (c *Cache) Has(key string) bool {
+	c.Lock()
+	defer c.Unlock()
+
 	_, ok := c.holds[key]
 	return ok
 }
 
 func (c *Cache) Range() (a []interface{}) {
+	c.Lock()
+	defer c.Unlock()
+
 	for _, v := range c.holds {
 		a = append(a, v.item)
 	}
@@ -83,16 +108,17 @@ func (c *Cache) Range() (a []interface{}) {
 }
 
 func (c *Cache) clearLRU(exception string) {
+	c.Lock()
+	defer c.Unlock()
+
 	// don't clear the cache if we haven't reached full capacity
-	if c.Size() < c.capacity {
+	if len(c.holds) < c.capacity {
 		return
 	}
 	// also don't clear if it's an infinite cache, i.e capacity is 0
 	if c.capacity == 0 {
 		return
 	}
-	c.Lock()
-	defer c.Unlock()
 	// primitive sorting, there's gotta be a better way to do this
 	var lowest string
 	for key := range c.holds {
@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentAccess(t *testing.T) {
+	c := NewCache(0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				id := strconv.Itoa(i*100 + j)
+				c.Add(id, j)
+				c.Update(id, j+1)
+				c.Has(id)
+				c.Range()
+				c.Size()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if c.Size() != 400 {
+		t.Errorf("expected 400 items, received %d", c.Size())
+	}
+}
@@ -1,6 +1,7 @@
 package gocord
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -26,8 +27,85 @@ const (
 	MessageTypeGuildMemberJoin
 )
 
+type ChannelType int
+
+// Channel types, as documented at https://discordapp.com/developers/docs/resources/channel#channel-object-channel-types
+const (
+	ChannelTypeGuildText ChannelType = iota
+	ChannelTypeDM
+	ChannelTypeGuildVoice
+	ChannelTypeGroupDM
+	ChannelTypeGuildCategory
+	ChannelTypeGuildNews
+	ChannelTypeGuildStore
+)
+
+// Permission overwrite types
+const (
+	OverwriteTypeRole   = "role"
+	OverwriteTypeMember = "member"
+)
+
 // Channel represents a generic Discord channel
 type Channel struct {
+	ID                   string                `json:"id"`
+	Type                 ChannelType           `json:"type"`
+	GuildID              string                `json:"guild_id,omitempty"`
+	Position             int                   `json:"position"`
+	PermissionOverwrites []PermissionOverwrite `json:"permission_overwrites,omitempty"`
+	Name                 string                `json:"name,omitempty"`
+	Topic                string                `json:"topic,omitempty"`
+	NSFW                 bool                  `json:"nsfw"`
+	LastMessageID        string                `json:"last_message_id,omitempty"`
+	Bitrate              int                   `json:"bitrate,omitempty"`             // voice channels only
+	UserLimit            int                   `json:"user_limit,omitempty"`          // voice channels only
+	RateLimitPerUser     int                   `json:"rate_limit_per_user,omitempty"` // the slowmode, in seconds
+	Recipients           []*User               `json:"recipients,omitempty"`          // DM channels only
+	Icon                 string                `json:"icon,omitempty"`
+	OwnerID              string                `json:"owner_id,omitempty"`
+	ParentID             string                `json:"parent_id,omitempty"` // the ID of the category
+	LastPinTimestamp     string                `json:"last_pin_timestamp,omitempty"`
+}
+
+// PermissionOverwrite overrides the permissions of a role or a member in a channel
+type PermissionOverwrite struct {
+	ID    string `json:"id"`   // the ID of the role or the member
+	Type  string `json:"type"` // OverwriteTypeRole or OverwriteTypeMember
+	Allow int    `json:"allow"`
+	Deny  int    `json:"deny"`
+}
+
+// ModifyChannel are the settings of a channel to modify, nil fields are left unchanged
+type ModifyChannel struct {
+	Name                 string                `json:"name,omitempty"`
+	Position             *int                  `json:"position,omitempty"`
+	Topic                *string               `json:"topic,omitempty"`
+	NSFW                 *bool                 `json:"nsfw,omitempty"`
+	RateLimitPerUser     *int                  `json:"rate_limit_per_user,omitempty"`
+	Bitrate              *int                  `json:"bitrate,omitempty"`
+	UserLimit            *int                  `json:"user_limit,omitempty"`
+	PermissionOverwrites []PermissionOverwrite `json:"permission_overwrites,omitempty"`
+	ParentID             *string               `json:"parent_id,omitempty"`
+}
+
+// CreateGuildChannel are the settings of a new guild channel
+type CreateGuildChannel struct {
+	Name                 string                `json:"name"`
+	Type                 ChannelType           `json:"type"`
+	Topic                string                `json:"topic,omitempty"`
+	Bitrate              int                   `json:"bitrate,omitempty"`
+	UserLimit            int                   `json:"user_limit,omitempty"`
+	RateLimitPerUser     int                   `json:"rate_limit_per_user,omitempty"`
+	Position             int                   `json:"position,omitempty"`
+	PermissionOverwrites []PermissionOverwrite `json:"permission_overwrites,omitempty"`
+	ParentID             string                `json:"parent_id,omitempty"`
+	NSFW                 bool                  `json:"nsfw,omitempty"`
+}
+
+// ChannelPosition is the new position of a channel, used when reordering the channels of a guild
+type ChannelPosition struct {
+	ID       string `json:"id"`
+	Position int    `json:"position"`
 }
 
 type Message struct {
@@ -64,27 +142,47 @@ func (m *Message) EditedAt() (time.Time, error) {
 
 // CreateMessage sends a message to the specified channel
 func (c *Cluster) CreateMessage(channelID string, message string) (*Message, error) {
-	return c.CreateMessageComplex(CreateMessage{
+	return c.CreateMessageContext(context.Background(), channelID, message)
+}
+
+// CreateMessageContext is CreateMessage with a context
+func (c *Cluster) CreateMessageContext(ctx context.Context, channelID string, message string) (*Message, error) {
+	return c.CreateMessageComplexContext(ctx, CreateMessage{
 		ChannelID: channelID,
 		Content:   message,
 	})
 }
 
 func (c *Cluster) CreateMessageFile(channelID string, files ...rest.File) (*Message, error) {
-	return c.CreateMessageComplex(CreateMessage{
+	return c.CreateMessageFileContext(context.Background(), channelID, files...)
+}
+
+// CreateMessageFileContext is CreateMessageFile with a context
+func (c *Cluster) CreateMessageFileContext(ctx context.Context, channelID string, files ...rest.File) (*Message, error) {
+	return c.CreateMessageComplexContext(ctx, CreateMessage{
 		ChannelID: channelID,
 		Files:     files,
 	})
 }
 
 func (c *Cluster) CreateMessageEmbed(channelID string, embed *embeds.Embed) (*Message, error) {
-	return c.CreateMessageComplex(CreateMessage{
+	return c.CreateMessageEmbedContext(context.Background(), channelID, embed)
+}
+
+// CreateMessageEmbedContext is CreateMessageEmbed with a context
+func (c *Cluster) CreateMessageEmbedContext(ctx context.Context, channelID string, embed *embeds.Embed) (*Message, error) {
+	return c.CreateMessageComplexContext(ctx, CreateMessage{
 		ChannelID: channelID,
 		Embed:     embed,
 	})
 }
 
-func (s *Cluster) CreateMessageComplex(c CreateMessage) (m *Message, err error) {
+func (s *Cluster) CreateMessageComplex(c CreateMessage) (*Message, error) {
+	return s.CreateMessageComplexContext(context.Background(), c)
+}
+
+// CreateMessageComplexContext is CreateMessageComplex with a context
+func (s *Cluster) CreateMessageComplexContext(ctx context.Context, c CreateMessage) (m *Message, err error) {
 	endpoint := rest.ChannelMessages(c.ChannelID)
 
 	body, err := json.Marshal(&struct {
@@ -95,11 +193,19 @@ func (s *Cluster) CreateMessageComplex(c CreateMessage) (m *Message, err error)
 		return
 	}
 
-	err = s.Rest.Do(http.MethodPost, endpoint, body, &m, c.Files...)
+	err = s.Rest.DoContext(ctx, http.MethodPost, endpoint, body, &m, c.Files...)
 	return
 }
 
+// EditMessage edits the content of a message, returning nil when the request fails. Use
+// EditMessageContext to receive the error
 func (c *Cluster) EditMessage(channelID, messageID, message string) (m *Message) {
+	m, _ = c.EditMessageContext(context.Background(), channelID, messageID, message)
+	return
+}
+
+// EditMessageContext is EditMessage with a context, returning the error of the request
+func (c *Cluster) EditMessageContext(ctx context.Context, channelID, messageID, message string) (m *Message, err error) {
 	endpoint := rest.ChannelMessage(messageID, channelID)
 
 	body, err := json.Marshal(&struct {
@@ -109,24 +215,30 @@ func (c *Cluster) EditMessage(channelID, messageID, message string) (m *Message)
 		return
 	}
 
-	err = c.Rest.Do(http.MethodPatch, endpoint, body, &m)
-	if err != nil {
-		return
-	}
-
+	err = c.Rest.DoContext(ctx, http.MethodPatch, endpoint, body, &m)
 	return
 }
 
-func (c *Cluster) CreateReaction(channelID, messageID, emoji string) (err error) {
+func (c *Cluster) CreateReaction(channelID, messageID, emoji string) error {
+	return c.CreateReactionContext(context.Background(), channelID, messageID, emoji)
+}
+
+// CreateReactionContext is CreateReaction with a context
+func (c *Cluster) CreateReactionContext(ctx context.Context, channelID, messageID, emoji string) (err error) {
 	endpoint := rest.ChannelMessageReactions("@me", channelID, messageID, emoji)
-	err = c.Rest.Do(http.MethodPut, endpoint, nil, nil)
+	err = c.Rest.DoContext(ctx, http.MethodPut, endpoint, nil, nil)
 
 	return
 }
 
-func (c *Cluster) RemoveReaction(userID, channelID, messageID, emoji string) (err error) {
+func (c *Cluster) RemoveReaction(userID, channelID, messageID, emoji string) error {
+	return c.RemoveReactionContext(context.Background(), userID, channelID, messageID, emoji)
+}
+
+// RemoveReactionContext is RemoveReaction with a context
+func (c *Cluster) RemoveReactionContext(ctx context.Context, userID, channelID, messageID, emoji string) (err error) {
 	endpoint := rest.ChannelMessageReactions(userID, channelID, messageID, emoji)
-	err = c.Rest.Do(http.MethodDelete, endpoint, nil, nil)
+	err = c.Rest.DoContext(ctx, http.MethodDelete, endpoint, nil, nil)
 
 	return
 }
@@ -135,33 +247,258 @@ func (c *Cluster) RemoveOwnReaction(channelID, messageID, emoji string) error {
 	return c.RemoveReaction("@me", channelID, messageID, emoji)
 }
 
-func (c *Cluster) RemoveAllReactions(channelID, messageID string) (err error) {
+// RemoveOwnReactionContext is RemoveOwnReaction with a context
+func (c *Cluster) RemoveOwnReactionContext(ctx context.Context, channelID, messageID, emoji string) error {
+	return c.RemoveReactionContext(ctx, "@me", channelID, messageID, emoji)
+}
+
+func (c *Cluster) RemoveAllReactions(channelID, messageID string) error {
+	return c.RemoveAllReactionsContext(context.Background(), channelID, messageID)
+}
+
+// RemoveAllReactionsContext is RemoveAllReactions with a context
+func (c *Cluster) RemoveAllReactionsContext(ctx context.Context, channelID, messageID string) (err error) {
 	endpoint := rest.ChannelMessageReactionsAll(channelID, messageID)
-	err = c.Rest.Do(http.MethodDelete, endpoint, nil, nil)
+	err = c.Rest.DoContext(ctx, http.MethodDelete, endpoint, nil, nil)
 
 	return
 }
 
-func (c *Cluster) DeleteMessage(channelID, messageID string) (err error) {
+// DeleteMessage deletes a message. Pass rest.WithReason to record a reason in the audit log
+func (c *Cluster) DeleteMessage(channelID, messageID string, options ...rest.RequestOption) error {
+	return c.DeleteMessageContext(context.Background(), channelID, messageID, options...)
+}
+
+// DeleteMessageContext is DeleteMessage with a context
+func (c *Cluster) DeleteMessageContext(ctx context.Context, channelID, messageID string, options ...rest.RequestOption) (err error) {
 	endpoint := rest.ChannelMessage(messageID, channelID)
-	err = c.Rest.Do(http.MethodDelete, endpoint, nil, nil)
+	err = c.Rest.DoWithOptions(ctx, http.MethodDelete, endpoint, nil, nil, options...)
 
 	return
 }
 
-func (c *Cluster) BulkDeleteMessages(channelID string, amount int) (err error) {
-	if amount < 2 || amount > 100 {
-		return errors.New("amount must be between 2 and 100")
+// BulkDeleteMessages deletes 2 to 100 messages at once. Messages older than 14 days can't be bulk deleted,
+// see PurgeMessages. Pass rest.WithReason to record a reason in the audit log
+func (c *Cluster) BulkDeleteMessages(channelID string, messageIDs []string, options ...rest.RequestOption) error {
+	return c.BulkDeleteMessagesContext(context.Background(), channelID, messageIDs, options...)
+}
+
+// BulkDeleteMessagesContext is BulkDeleteMessages with a context
+func (c *Cluster) BulkDeleteMessagesContext(ctx context.Context, channelID string, messageIDs []string, options ...rest.RequestOption) (err error) {
+	if len(messageIDs) < 2 || len(messageIDs) > 100 {
+		return errors.New("between 2 and 100 messages must be bulk deleted")
 	}
 	endpoint := rest.ChannelBulkDelete(channelID)
 
 	body, err := json.Marshal(&struct {
-		Messages int `json:"messages"`
-	}{amount})
+		Messages []string `json:"messages"`
+	}{messageIDs})
+	if err != nil {
+		return
+	}
+
+	err = c.Rest.DoWithOptions(ctx, http.MethodPost, endpoint, body, nil, options...)
+	return
+}
+
+// BulkDeleteMaxAge is the age of the oldest message that can be bulk deleted
+const BulkDeleteMaxAge = 14 * 24 * time.Hour
+
+// bulkDeleteMargin keeps messages about to become too old out of bulk deletes
+const bulkDeleteMargin = time.Minute
+
+// PurgeMessages deletes up to limit messages of a channel the filter matches, from the newest to the oldest,
+// and returns the number of messages deleted. A nil filter matches every message, and a limit of 0 walks
+// the whole history. Messages are bulk deleted by 100, except the ones older than 14 days which are deleted
+// one by one. Pass rest.WithReason to record a reason in the audit log
+func (c *Cluster) PurgeMessages(channelID string, filter func(*Message) bool, limit int, options ...rest.RequestOption) (int, error) {
+	return c.PurgeMessagesContext(context.Background(), channelID, filter, limit, options...)
+}
+
+// PurgeMessagesContext is PurgeMessages with a context
+func (c *Cluster) PurgeMessagesContext(ctx context.Context, channelID string, filter func(*Message) bool, limit int, options ...rest.RequestOption) (deleted int, err error) {
+	cutoff := time.Now().Add(-BulkDeleteMaxAge + bulkDeleteMargin)
+
+	// deletes the pending messages, a single one being deleted alone
+	var pending []string
+	flush := func() error {
+		var err error
+		if len(pending) == 1 {
+			err = c.DeleteMessageContext(ctx, channelID, pending[0], options...)
+		} else if len(pending) > 1 {
+			err = c.BulkDeleteMessagesContext(ctx, channelID, pending, options...)
+		}
+		if err == nil {
+			deleted += len(pending)
+			pending = nil
+		}
+		return err
+	}
+
+	matched := 0
+	it := c.MessagesBeforeContext(ctx, channelID, "", 0)
+	for it.Next() {
+		for _, m := range it.Batch() {
+			if filter != nil && !filter(m) {
+				continue
+			}
+
+			created, err := SnowflakeTime(m.ID)
+			if err != nil {
+				return deleted, err
+			}
+
+			if created.After(cutoff) {
+				pending = append(pending, m.ID)
+				if len(pending) == 100 {
+					if err := flush(); err != nil {
+						return deleted, err
+					}
+				}
+			} else {
+				// the history is walked from the newest message, every message left is too old as well
+				if err := flush(); err != nil {
+					return deleted, err
+				}
+				if err := c.DeleteMessageContext(ctx, channelID, m.ID, options...); err != nil {
+					return deleted, err
+				}
+				deleted++
+			}
+
+			matched++
+			if matched == limit {
+				return deleted, flush()
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return deleted, err
+	}
+
+	return deleted, flush()
+}
+
+// FetchChannel fetches a channel given an ID
+func (c *Cluster) FetchChannel(channelID string) (*Channel, error) {
+	return c.FetchChannelContext(context.Background(), channelID)
+}
+
+// FetchChannelContext is FetchChannel with a context
+func (c *Cluster) FetchChannelContext(ctx context.Context, channelID string) (ch *Channel, err error) {
+	endpoint := rest.Channel(channelID)
+	err = c.Rest.DoContext(ctx, http.MethodGet, endpoint, nil, &ch)
+	return
+}
+
+// FetchGuildChannels fetches the channels of a guild
+func (c *Cluster) FetchGuildChannels(guildID string) ([]*Channel, error) {
+	return c.FetchGuildChannelsContext(context.Background(), guildID)
+}
+
+// FetchGuildChannelsContext is FetchGuildChannels with a context
+func (c *Cluster) FetchGuildChannelsContext(ctx context.Context, guildID string) (channels []*Channel, err error) {
+	endpoint := rest.GuildChannels(guildID)
+	err = c.Rest.DoContext(ctx, http.MethodGet, endpoint, nil, &channels)
+	return
+}
+
+// ModifyChannel updates the settings of a channel. Pass rest.WithReason to record a reason in the audit log
+func (c *Cluster) ModifyChannel(channelID string, data ModifyChannel, options ...rest.RequestOption) (*Channel, error) {
+	return c.ModifyChannelContext(context.Background(), channelID, data, options...)
+}
+
+// ModifyChannelContext is ModifyChannel with a context
+func (c *Cluster) ModifyChannelContext(ctx context.Context, channelID string, data ModifyChannel, options ...rest.RequestOption) (ch *Channel, err error) {
+	endpoint := rest.Channel(channelID)
+
+	body, err := json.Marshal(&data)
+	if err != nil {
+		return
+	}
+
+	err = c.Rest.DoWithOptions(ctx, http.MethodPatch, endpoint, body, &ch, options...)
+	return
+}
+
+// DeleteChannel deletes a guild channel, or closes a DM. Pass rest.WithReason to record a reason in the audit log
+func (c *Cluster) DeleteChannel(channelID string, options ...rest.RequestOption) (*Channel, error) {
+	return c.DeleteChannelContext(context.Background(), channelID, options...)
+}
+
+// DeleteChannelContext is DeleteChannel with a context
+func (c *Cluster) DeleteChannelContext(ctx context.Context, channelID string, options ...rest.RequestOption) (ch *Channel, err error) {
+	endpoint := rest.Channel(channelID)
+	err = c.Rest.DoWithOptions(ctx, http.MethodDelete, endpoint, nil, &ch, options...)
+	return
+}
+
+// CreateGuildChannel creates a channel in a guild. Pass rest.WithReason to record a reason in the audit log
+func (c *Cluster) CreateGuildChannel(guildID string, data CreateGuildChannel, options ...rest.RequestOption) (*Channel, error) {
+	return c.CreateGuildChannelContext(context.Background(), guildID, data, options...)
+}
+
+// CreateGuildChannelContext is CreateGuildChannel with a context
+func (c *Cluster) CreateGuildChannelContext(ctx context.Context, guildID string, data CreateGuildChannel, options ...rest.RequestOption) (ch *Channel, err error) {
+	endpoint := rest.GuildChannels(guildID)
+
+	body, err := json.Marshal(&data)
+	if err != nil {
+		return
+	}
+
+	err = c.Rest.DoWithOptions(ctx, http.MethodPost, endpoint, body, &ch, options...)
+	return
+}
+
+// ModifyChannelPositions reorders the channels of a guild. Only the channels moved need to be passed
+func (c *Cluster) ModifyChannelPositions(guildID string, positions []ChannelPosition, options ...rest.RequestOption) error {
+	return c.ModifyChannelPositionsContext(context.Background(), guildID, positions, options...)
+}
+
+// ModifyChannelPositionsContext is ModifyChannelPositions with a context
+func (c *Cluster) ModifyChannelPositionsContext(ctx context.Context, guildID string, positions []ChannelPosition, options ...rest.RequestOption) (err error) {
+	endpoint := rest.GuildChannels(guildID)
+
+	body, err := json.Marshal(positions)
+	if err != nil {
+		return
+	}
+
+	err = c.Rest.DoWithOptions(ctx, http.MethodPatch, endpoint, body, nil, options...)
+	return
+}
+
+// EditChannelPermissions creates or replaces the permission overwrite of a role or a member in a channel
+func (c *Cluster) EditChannelPermissions(channelID string, overwrite PermissionOverwrite, options ...rest.RequestOption) error {
+	return c.EditChannelPermissionsContext(context.Background(), channelID, overwrite, options...)
+}
+
+// EditChannelPermissionsContext is EditChannelPermissions with a context
+func (c *Cluster) EditChannelPermissionsContext(ctx context.Context, channelID string, overwrite PermissionOverwrite, options ...rest.RequestOption) (err error) {
+	endpoint := rest.ChannelPermission(channelID, overwrite.ID)
+
+	body, err := json.Marshal(&struct {
+		Allow int    `json:"allow"`
+		Deny  int    `json:"deny"`
+		Type  string `json:"type"`
+	}{overwrite.Allow, overwrite.Deny, overwrite.Type})
 	if err != nil {
 		return
 	}
 
-	err = c.Rest.Do(http.MethodPost, endpoint, body, nil)
+	err = c.Rest.DoWithOptions(ctx, http.MethodPut, endpoint, body, nil, options...)
+	return
+}
+
+// DeleteChannelPermission deletes the permission overwrite of a role or a member in a channel
+func (c *Cluster) DeleteChannelPermission(channelID, overwriteID string, options ...rest.RequestOption) error {
+	return c.DeleteChannelPermissionContext(context.Background(), channelID, overwriteID, options...)
+}
+
+// DeleteChannelPermissionContext is DeleteChannelPermission with a context
+func (c *Cluster) DeleteChannelPermissionContext(ctx context.Context, channelID, overwriteID string, options ...rest.RequestOption) (err error) {
+	endpoint := rest.ChannelPermission(channelID, overwriteID)
+	err = c.Rest.DoWithOptions(ctx, http.MethodDelete, endpoint, nil, nil, options...)
 	return
 }
@@ -0,0 +1,240 @@
+package gocord
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Soumil07/gocord/rest"
+)
+
+func TestChannelEndpoints(t *testing.T) {
+	c, requests := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut || (r.Method == http.MethodDelete && r.URL.Path == "/v6/channels/1/permissions/2") {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`{"id": "1", "type": 0, "name": "general", "position": 2, "nsfw": true, "parent_id": "9",
+			"permission_overwrites": [{"id": "2", "type": "role", "allow": 1024, "deny": 2048}]}`))
+	})
+
+	topic := ""
+	slowmode := 10
+	tests := []struct {
+		name   string
+		call   func() error
+		method string
+		path   string
+		body   string
+		reason string
+	}{
+		{
+			name:   "fetch",
+			call:   func() error { _, err := c.FetchChannel("1"); return err },
+			method: http.MethodGet,
+			path:   "/v6/channels/1",
+		},
+		{
+			name: "modify",
+			call: func() error {
+				_, err := c.ModifyChannel("1", ModifyChannel{Name: "rules", Topic: &topic, RateLimitPerUser: &slowmode}, rest.WithReason("cleanup"))
+				return err
+			},
+			method: http.MethodPatch,
+			path:   "/v6/channels/1",
+			body:   `{"name":"rules","topic":"","rate_limit_per_user":10}`,
+			reason: "cleanup",
+		},
+		{
+			name:   "delete",
+			call:   func() error { _, err := c.DeleteChannel("1"); return err },
+			method: http.MethodDelete,
+			path:   "/v6/channels/1",
+		},
+		{
+			name: "create",
+			call: func() error {
+				_, err := c.CreateGuildChannel("5", CreateGuildChannel{Name: "voice", Type: ChannelTypeGuildVoice, UserLimit: 5})
+				return err
+			},
+			method: http.MethodPost,
+			path:   "/v6/guilds/5/channels",
+			body:   `{"name":"voice","type":2,"user_limit":5}`,
+		},
+		{
+			name: "reorder",
+			call: func() error {
+				return c.ModifyChannelPositions("5", []ChannelPosition{{"1", 0}, {"2", 1}})
+			},
+			method: http.MethodPatch,
+			path:   "/v6/guilds/5/channels",
+			body:   `[{"id":"1","position":0},{"id":"2","position":1}]`,
+		},
+		{
+			name: "edit permissions",
+			call: func() error {
+				return c.EditChannelPermissions("1", PermissionOverwrite{ID: "2", Type: OverwriteTypeMember, Allow: PermissionsSendMessages})
+			},
+			method: http.MethodPut,
+			path:   "/v6/channels/1/permissions/2",
+			body:   `{"allow":2048,"deny":0,"type":"member"}`,
+		},
+		{
+			name:   "delete permissions",
+			call:   func() error { return c.DeleteChannelPermission("1", "2", rest.WithReason("reset")) },
+			method: http.MethodDelete,
+			path:   "/v6/channels/1/permissions/2",
+			reason: "reset",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.call(); err != nil {
+				t.Fatal(err)
+			}
+
+			req := (*requests)[len(*requests)-1]
+			if req.Method != test.method || req.URL.Path != test.path {
+				t.Errorf("unexpected request: %s %s", req.Method, req.URL.Path)
+			}
+			if req.Body != test.body {
+				t.Errorf("unexpected body: %s", req.Body)
+			}
+			if reason := req.Header.Get("X-Audit-Log-Reason"); reason != test.reason {
+				t.Errorf("unexpected reason: %q", reason)
+			}
+		})
+	}
+
+	t.Run("model", func(t *testing.T) {
+		ch, err := c.FetchChannel("1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := PermissionOverwrite{ID: "2", Type: OverwriteTypeRole, Allow: PermissionsViewChannel, Deny: PermissionsSendMessages}
+		if ch.Name != "general" || ch.Position != 2 || !ch.NSFW || ch.ParentID != "9" || len(ch.PermissionOverwrites) != 1 || ch.PermissionOverwrites[0] != expected {
+			encoded, _ := json.Marshal(ch)
+			t.Errorf("unexpected channel: %s", encoded)
+		}
+	})
+}
+
+// snowflakeAt returns a snowflake created at a time
+func snowflakeAt(t time.Time, increment int) string {
+	ms := uint64(t.UnixNano()/int64(time.Millisecond) - DiscordEpoch)
+	return strconv.FormatUint(ms<<22|uint64(increment), 10)
+}
+
+func TestSnowflakeTime(t *testing.T) {
+	created, err := SnowflakeTime("175928847299117063")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(2016, 4, 30, 11, 18, 25, 796000000, time.UTC); !created.Equal(expected) {
+		t.Errorf("expected %s, got %s", expected, created.UTC())
+	}
+}
+
+func TestBulkDeleteMessages(t *testing.T) {
+	c, requests := newTestAPI(t, nil)
+
+	if err := c.BulkDeleteMessages("1", []string{"2", "3"}, rest.WithReason("spam")); err != nil {
+		t.Fatal(err)
+	}
+	req := (*requests)[0]
+	if req.Method != http.MethodPost || req.URL.Path != "/v6/channels/1/messages/bulk-delete" || req.Body != `{"messages":["2","3"]}` {
+		t.Errorf("unexpected request: %s %s %s", req.Method, req.URL.Path, req.Body)
+	}
+
+	if err := c.BulkDeleteMessages("1", []string{"2"}); err == nil || len(*requests) != 1 {
+		t.Error("expected a single message not to be bulk deleted")
+	}
+}
+
+func TestPurgeMessages(t *testing.T) {
+	// the channel holds 253 recent messages, then 2 messages older than 14 days, newest first, with a message
+	// to keep every 10 messages
+	var messages []Message
+	for i := 0; i < 255; i++ {
+		created := time.Now().Add(-time.Duration(i) * time.Minute)
+		if i >= 253 {
+			created = time.Now().Add(-15 * 24 * time.Hour)
+		}
+		content := "purge"
+		if i%10 == 9 {
+			content = "keep"
+		}
+		messages = append(messages, Message{ID: snowflakeAt(created, 255-i), Content: content})
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		before := r.URL.Query().Get("before")
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		page := []Message{}
+		for _, m := range messages {
+			if (before == "" || snowflakeLess(m.ID, before)) && len(page) < limit {
+				page = append(page, m)
+			}
+		}
+		json.NewEncoder(w).Encode(page)
+	}
+	purge := func(m *Message) bool { return m.Content == "purge" }
+
+	// summary lists the deletes sent, the number of messages bulk deleted or 1 for single deletes
+	summary := func(requests []apiRequest) string {
+		var deletes []string
+		for _, req := range requests {
+			switch req.Method {
+			case http.MethodPost:
+				var body struct{ Messages []string }
+				json.Unmarshal([]byte(req.Body), &body)
+				deletes = append(deletes, strconv.Itoa(len(body.Messages)))
+			case http.MethodDelete:
+				deletes = append(deletes, "1")
+			}
+		}
+		return strings.Join(deletes, " ")
+	}
+
+	tests := []struct {
+		limit   int
+		deleted int
+		deletes string
+	}{
+		{limit: 0, deleted: 230, deletes: "100 100 28 1 1"},
+		{limit: 150, deleted: 150, deletes: "100 50"},
+		{limit: 101, deleted: 101, deletes: "100 1"},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("limit %d", test.limit), func(t *testing.T) {
+			c, requests := newTestAPI(t, handler)
+
+			deleted, err := c.PurgeMessages("1", purge, test.limit, rest.WithReason("cleanup"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if deleted != test.deleted {
+				t.Errorf("expected %d messages deleted, got %d", test.deleted, deleted)
+			}
+			if deletes := summary(*requests); deletes != test.deletes {
+				t.Errorf("expected deletes %q, got %q", test.deletes, deletes)
+			}
+			for _, req := range *requests {
+				if req.Method != http.MethodGet && req.Header.Get("X-Audit-Log-Reason") != "cleanup" {
+					t.Errorf("the reason wasn't sent with %s %s", req.Method, req.URL.Path)
+				}
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package gocord
 
 import (
 	"bufio"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -19,10 +20,15 @@ import (
 	"github.com/Soumil07/gocord/rest"
 )
 
-func (c *Cluster) LeaveGuild(guildID string) (err error) {
+func (c *Cluster) LeaveGuild(guildID string) error {
+	return c.LeaveGuildContext(context.Background(), guildID)
+}
+
+// LeaveGuildContext is LeaveGuild with a context
+func (c *Cluster) LeaveGuildContext(ctx context.Context, guildID string) (err error) {
 	endpoint := rest.UserGuild("@me", guildID)
 
-	err = c.Rest.Do(http.MethodDelete, endpoint, nil, nil)
+	err = c.Rest.DoContext(ctx, http.MethodDelete, endpoint, nil, nil)
 	if err != nil {
 		return
 	}
@@ -30,7 +36,12 @@ func (c *Cluster) LeaveGuild(guildID string) (err error) {
 	return
 }
 
-func (c *Cluster) ModifyUser(username, avatar string) (u *User, err error) {
+func (c *Cluster) ModifyUser(username, avatar string) (*User, error) {
+	return c.ModifyUserContext(context.Background(), username, avatar)
+}
+
+// ModifyUserContext is ModifyUser with a context
+func (c *Cluster) ModifyUserContext(ctx context.Context, username, avatar string) (u *User, err error) {
 	endpoint := rest.User("@me")
 	body, err := json.Marshal(&struct {
 		Username string `json:"username"`
@@ -41,11 +52,16 @@ func (c *Cluster) ModifyUser(username, avatar string) (u *User, err error) {
 		return
 	}
 
-	err = c.Rest.Do(http.MethodPatch, endpoint, body, &u)
+	err = c.Rest.DoContext(ctx, http.MethodPatch, endpoint, body, &u)
 	return
 }
 
-func (c *Cluster) SetAvatar(avatar io.Reader) (u *User, err error) {
+func (c *Cluster) SetAvatar(avatar io.Reader) (*User, error) {
+	return c.SetAvatarContext(context.Background(), avatar)
+}
+
+// SetAvatarContext is SetAvatar with a context
+func (c *Cluster) SetAvatarContext(ctx context.Context, avatar io.Reader) (u *User, err error) {
 	_, ext, err := image.Decode(avatar)
 	if err != nil {
 		return
@@ -59,9 +75,14 @@ func (c *Cluster) SetAvatar(avatar io.Reader) (u *User, err error) {
 	encoded := base64.StdEncoding.EncodeToString(content)
 	data := fmt.Sprintf("data:image/%s;base64;%s", strings.ToLower(ext), encoded)
 
-	return c.ModifyUser("", data)
+	return c.ModifyUserContext(ctx, "", data)
 }
 
 func (c *Cluster) SetUsername(username string) (*User, error) {
 	return c.ModifyUser(username, "")
 }
+
+// SetUsernameContext is SetUsername with a context
+func (c *Cluster) SetUsernameContext(ctx context.Context, username string) (*User, error) {
+	return c.ModifyUserContext(ctx, username, "")
+}
@@ -1,14 +1,23 @@
 package gocord
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"sync"
-  "github.com/Soumil07/gocord/rest"
+	"time"
+
+	"github.com/Soumil07/gocord/rest"
 	eventemitter "github.com/euskadi31/go-eventemitter"
 )
 
+// identifyInterval is the minimum interval between identifies in the same rate limit bucket
+const identifyInterval = 5 * time.Second
+
+// ErrIdentifyLimitExhausted is returned by Spawn when the daily identify budget can't start every shard
+var ErrIdentifyLimitExhausted = errors.New("the daily session start limit is exhausted")
+
 // Cluster of Shards connecting to the gateway
 type Cluster struct {
 	*eventemitter.Emitter
@@ -17,8 +26,18 @@ type Cluster struct {
 	TotalShards int
 	GatewayURL  string
 	Options     ClusterOptions
-  Rest *rest.RestManager
-	handlers sync.Map // event handlers
+	Rest        *rest.RestManager
+	handlers    sync.Map // names of the subscribed events
+
+	SessionStartLimit SessionStartLimit // the identify budget, fetched when the cluster is created
+	identifyBuckets   []*identifyBucket
+	identifyOnce      sync.Once
+}
+
+// identifyBucket spaces identifies of shards sharing the same max_concurrency bucket
+type identifyBucket struct {
+	sync.Mutex
+	last time.Time // when the last identify of the bucket was sent
 }
 
 // ClusterOptions are the options used in the cluster
@@ -26,38 +45,81 @@ type ClusterOptions struct {
 	Shards      []int // an array of shard IDs
 	TotalShards int   // the total shards to spawn
 	Presence    Presence
-	Debug       bool // set to true during debug mode ONLY, this will log a lot of (useful) stuff such as reconnects and headers
+	// Intents are the gateway intents to identify with, worked out from the subscribed events when 0. The
+	// intents the library depends on are always added
+	Intents     Intents
+	Compression bool     // whether to use zlib-stream transport compression, reducing bandwidth at the cost of CPU
+	Encoding    Encoding // the encoding payloads are exchanged in, JSONEncoding when nil
+	// ChunkGuilds requests every member of large guilds once they are received. Requires the guild members intent
+	ChunkGuilds bool
+	// MemberRequestTimeout is the time to wait for the chunks of a member request, DefaultMemberRequestTimeout when 0
+	MemberRequestTimeout time.Duration
+	Rest                 []rest.Option // configures the rest manager, such as its base URL and HTTP client
+	Debug                bool          // set to true during debug mode ONLY, this will log a lot of (useful) stuff such as reconnects and headers
 }
 
 func (c *Cluster) fetchRecommendedShards() int {
-	req, _ := http.NewRequest(http.MethodGet, RestURL+gatewayPath, nil)
-	req.Header.Add("Authorization", fmt.Sprintf("Bot %s", c.Token))
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		panic(err)
-	}
-
-	defer resp.Body.Close()
-	decoder := json.NewDecoder(resp.Body)
 	var decoded gatewayPayload
-	err = decoder.Decode(&decoded)
+	err := c.Rest.Do(http.MethodGet, gatewayPath, nil, &decoded)
 	if err != nil {
 		panic(err)
 	}
-	fmt.Printf("%#v", decoded)
 	c.GatewayURL = decoded.URL
+	c.SessionStartLimit = decoded.SessionStartLimit
 
 	return decoded.Shards
 }
 
+// errIdentifyAborted is returned when the connection ends while the shard waits to identify
+var errIdentifyAborted = errors.New("the connection ended before identifying")
+
+// waitIdentify calls identify once the shard is allowed to identify, and records when the identify was
+// sent. Shards are bucketed by their ID modulo max_concurrency, and every bucket may identify once every
+// 5 seconds. Waiting stops once stop is closed
+func (c *Cluster) waitIdentify(shardID int, stop <-chan struct{}, identify func() error) error {
+	c.identifyOnce.Do(func() {
+		concurrency := c.SessionStartLimit.MaxConcurrency
+		if concurrency < 1 {
+			concurrency = 1
+		}
+		c.identifyBuckets = make([]*identifyBucket, concurrency)
+		for i := range c.identifyBuckets {
+			c.identifyBuckets[i] = &identifyBucket{}
+		}
+	})
+
+	bucket := c.identifyBuckets[shardID%len(c.identifyBuckets)]
+	bucket.Lock()
+	defer bucket.Unlock()
+
+	if wait := time.Until(bucket.last.Add(identifyInterval)); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-stop:
+			return errIdentifyAborted
+		}
+	}
+
+	err := identify()
+	// the interval starts once the identify is on its way, however long it took to connect
+	bucket.last = time.Now()
+	return err
+}
+
 // NewCluster returns a cluster instance
 func NewCluster(token string, opts ClusterOptions) *Cluster {
 	cluster := &Cluster{
 		Emitter: eventemitter.New(),
 		Token:   token,
-    Rest: rest.NewRestManager(token),
+		Rest:    rest.NewRestManager(token, opts.Rest...),
 	}
 	cluster.Options = opts
+	cluster.Rest.OnRateLimit = func(limit *rest.RateLimit) {
+		cluster.Dispatch(rateLimitEvent, limit)
+	}
 	recShards := cluster.fetchRecommendedShards()
 
 	cluster.Shards = make(map[int]*Shard)
@@ -83,23 +145,83 @@ func NewCluster(token string, opts ClusterOptions) *Cluster {
 	return cluster
 }
 
-// Spawn starts all shards and returns a slice of errors returned from every shard
+// Spawn starts all shards concurrently, and blocks until every shard is closed. Identifies are spaced
+// following the session start limit, and Spawn refuses to start if the daily identify budget can't
+// start every shard. A slice of errors returned from every shard is returned
 func (c *Cluster) Spawn() []error {
+	limit := c.SessionStartLimit
+	if limit.Total != 0 && limit.Remaining < len(c.Shards) {
+		return []error{fmt.Errorf("%w: %d identifies remaining, resets in %s", ErrIdentifyLimitExhausted,
+			limit.Remaining, time.Duration(limit.ResetAfter)*time.Millisecond)}
+	}
+
+	// shards are started in order, so lower shard IDs identify first within their bucket
+	ids := make([]int, 0, len(c.Shards))
+	for id := range c.Shards {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
 	var wg sync.WaitGroup
+	var mu sync.Mutex
 	var out []error
 
-	for _, shard := range c.Shards {
+	for _, id := range ids {
 		wg.Add(1)
-		err := shard.Connect()
-		if err != nil {
-			out = append(out, err)
-		}
+		go func(shard *Shard) {
+			defer wg.Done()
+
+			err := shard.Connect()
+			if err != nil {
+				mu.Lock()
+				out = append(out, err)
+				mu.Unlock()
+			}
+		}(c.Shards[id])
 	}
 
 	wg.Wait()
 	return out
 }
 
+// Subscribe adds an event listener listening on the specified event
+func (c *Cluster) Subscribe(name string, listener interface{}) {
+	c.handlers.Store(name, struct{}{})
+	c.Emitter.Subscribe(name, listener)
+}
+
+// RequiredIntents returns the gateway intents required to receive every subscribed event, along with the
+// intents the library depends on
+func (c *Cluster) RequiredIntents() Intents {
+	var events []string
+	c.handlers.Range(func(name, _ interface{}) bool {
+		events = append(events, name.(string))
+		return true
+	})
+
+	return IntentsForEvents(events...) | c.libraryIntents()
+}
+
+// libraryIntents returns the intents the library depends on whatever the subscribed events: guilds fill
+// the guild cache, voice states complete voice channel joins and members are needed to chunk guilds
+func (c *Cluster) libraryIntents() Intents {
+	intents := IntentsGuilds | IntentsGuildVoiceStates
+	if c.Options.ChunkGuilds {
+		intents |= IntentsGuildMembers
+	}
+
+	return intents
+}
+
+// intents returns the intents shards identify with
+func (c *Cluster) intents() Intents {
+	if c.Options.Intents != 0 {
+		return c.Options.Intents | c.libraryIntents()
+	}
+
+	return c.RequiredIntents()
+}
+
 /* USEFUL SHARD-CLUSTER WRAPPERS */
 
 func (c *Cluster) Guilds() (n int) {
@@ -113,7 +235,7 @@ func (c *Cluster) Guilds() (n int) {
 func (c *Cluster) Members() (n int) {
 	for _, shard := range c.Shards {
 		for _, guild := range shard.GuildCache.Range() {
-			n += guild.(Guild).MemberCount
+			n += guild.(*Guild).MemberCount
 		}
 	}
 
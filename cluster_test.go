@@ -0,0 +1,100 @@
+package gocord
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSpawn(t *testing.T) {
+	t.Run("exhausted session start limit", func(t *testing.T) {
+		c := newTestCluster("")
+		c.Shards = map[int]*Shard{0: NewShard(0, c), 1: NewShard(1, c)}
+		c.SessionStartLimit = SessionStartLimit{Total: 1000, Remaining: 1, MaxConcurrency: 1}
+
+		errs := c.Spawn()
+		if len(errs) != 1 || !errors.Is(errs[0], ErrIdentifyLimitExhausted) {
+			t.Errorf("expected the identify limit to be exhausted, received %v", errs)
+		}
+	})
+
+	t.Run("identify buckets", func(t *testing.T) {
+		c := newTestCluster("")
+		c.SessionStartLimit = SessionStartLimit{MaxConcurrency: 2}
+
+		identify := func() error { return nil }
+		start := time.Now()
+		c.waitIdentify(0, nil, identify)
+		c.waitIdentify(1, nil, identify)
+		if time.Since(start) > time.Second {
+			t.Errorf("shards in different buckets should identify concurrently")
+		}
+	})
+
+	t.Run("same bucket", func(t *testing.T) {
+		identified := make(chan time.Time, 2)
+		upgrader := websocket.Upgrader{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ws, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer ws.Close()
+
+			ws.WriteJSON(map[string]interface{}{"op": OPCodeHello, "d": map[string]interface{}{"heartbeat_interval": 60000}})
+			for {
+				var payload receivePayload
+				if err := ws.ReadJSON(&payload); err != nil {
+					return
+				}
+				if payload.OP == OPCodeIdentify {
+					identified <- time.Now()
+				}
+			}
+		}))
+		defer server.Close()
+
+		c := newTestCluster("ws" + strings.TrimPrefix(server.URL, "http"))
+		c.SessionStartLimit = SessionStartLimit{MaxConcurrency: 1}
+		for id := 0; id < 2; id++ {
+			shard := NewShard(id, c)
+			go shard.Connect()
+			defer shard.Close()
+		}
+
+		var times []time.Time
+		for len(times) < 2 {
+			select {
+			case at := <-identified:
+				times = append(times, at)
+			case <-time.After(2 * identifyInterval):
+				t.Fatal("the shards did not identify")
+			}
+		}
+
+		// allows for the delivery of the first identify, its interval starting once it was written
+		if spacing := times[1].Sub(times[0]); spacing < identifyInterval-20*time.Millisecond {
+			t.Errorf("shards in the same bucket identified %s apart", spacing)
+		}
+	})
+
+	t.Run("aborted", func(t *testing.T) {
+		c := newTestCluster("")
+		c.waitIdentify(0, nil, func() error { return nil })
+
+		stop := make(chan struct{})
+		close(stop)
+		err := c.waitIdentify(0, stop, func() error {
+			t.Error("identified while the connection ended")
+			return nil
+		})
+		if err != errIdentifyAborted {
+			t.Errorf("expected errIdentifyAborted, received %v", err)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+// Command gocord-proxy serves the Discord REST API to several processes sharing a token, forwarding their
+// requests through a single rate limiter. Point gocord at it with rest.WithBaseURL
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/Soumil07/gocord/rest"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "the address to listen on")
+	token := flag.String("t", os.Getenv("DISCORD_TOKEN"), "the bot token requests are sent with, DISCORD_TOKEN by default")
+	passthrough := flag.Bool("passthrough", false, "forward the Authorization header of callers instead of using a token")
+	upstream := flag.String("upstream", rest.DefaultBaseURL, "the URL requests are forwarded to, without the API version")
+	store := flag.String("store", "", "a file keeping the rate limits, shared by every proxy using it")
+	flag.Parse()
+
+	if *token == "" && !*passthrough {
+		log.Fatal("No token provided, use -t or -passthrough.")
+	}
+
+	options := []rest.Option{rest.WithBaseURL(*upstream)}
+	if *store != "" {
+		// the store keeps the rate limits of a single token
+		if *passthrough {
+			log.Fatal("-store can't be used with -passthrough.")
+		}
+
+		fileStore, err := rest.NewFileStore(*store)
+		if err != nil {
+			log.Fatal(err)
+		}
+		options = append(options, rest.WithStore(fileStore))
+	}
+
+	proxy := NewProxy(*token, *passthrough, options...)
+
+	log.Printf("forwarding requests to %s on %s", *upstream, *addr)
+	log.Fatal(http.ListenAndServe(*addr, proxy.Handler()))
+}
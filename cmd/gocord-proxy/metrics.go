@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Soumil07/gocord/rest"
+)
+
+// Metrics are the counters of the proxy, served in the Prometheus text format
+type Metrics struct {
+	mu         sync.Mutex
+	requests   map[[2]string]int64 // requests by method and status code
+	rateLimits map[[2]string]int64 // rate limits hit by scope and whether they were global
+	duration   time.Duration       // the total time spent answering requests
+	answered   int64
+}
+
+// NewMetrics returns empty metrics
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests:   make(map[[2]string]int64),
+		rateLimits: make(map[[2]string]int64),
+	}
+}
+
+// request records an answered request
+func (m *Metrics) request(method string, status int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[[2]string{method, strconv.Itoa(status)}]++
+	m.duration += duration
+	m.answered++
+}
+
+// rateLimited records a rate limit hit while forwarding a request
+func (m *Metrics) rateLimited(limit *rest.RateLimit) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rateLimits[[2]string{limit.Scope, strconv.FormatBool(limit.Global)}]++
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP gocord_proxy_requests_total Requests answered by the proxy.")
+	fmt.Fprintln(w, "# TYPE gocord_proxy_requests_total counter")
+	for _, labels := range sortedKeys(m.requests) {
+		fmt.Fprintf(w, "gocord_proxy_requests_total{method=%q,status=%q} %d\n", labels[0], labels[1], m.requests[labels])
+	}
+
+	fmt.Fprintln(w, "# HELP gocord_proxy_rate_limits_total Rate limits hit while forwarding requests.")
+	fmt.Fprintln(w, "# TYPE gocord_proxy_rate_limits_total counter")
+	for _, labels := range sortedKeys(m.rateLimits) {
+		fmt.Fprintf(w, "gocord_proxy_rate_limits_total{scope=%q,global=%q} %d\n", labels[0], labels[1], m.rateLimits[labels])
+	}
+
+	fmt.Fprintln(w, "# HELP gocord_proxy_request_duration_seconds Time spent answering requests, including rate limit waits.")
+	fmt.Fprintln(w, "# TYPE gocord_proxy_request_duration_seconds summary")
+	fmt.Fprintf(w, "gocord_proxy_request_duration_seconds_sum %g\n", m.duration.Seconds())
+	fmt.Fprintf(w, "gocord_proxy_request_duration_seconds_count %d\n", m.answered)
+}
+
+// sortedKeys returns the labels of a counter in order, so the output is stable
+func sortedKeys(counter map[[2]string]int64) [][2]string {
+	keys := make([][2]string, 0, len(counter))
+	for key := range counter {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+
+	return keys
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Soumil07/gocord/rest"
+)
+
+// hopHeaders are the headers only meaningful for a single connection, which aren't forwarded
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+	"Content-Length",
+	"Accept-Encoding",
+}
+
+// Proxy forwards requests to Discord, sharing the rate limits of every caller
+type Proxy struct {
+	Metrics *Metrics
+
+	token       string
+	passthrough bool // whether callers send their own Authorization header
+	options     []rest.Option
+
+	mu        sync.Mutex
+	shared    *rest.RestManager                   // the manager of the token, outside of passthrough mode
+	managers  map[[sha256.Size]byte]*tokenManager // the managers of passthrough callers, by hashed Authorization header
+	lastSweep time.Time                           // when idle managers were last dropped
+}
+
+// the passthrough managers kept at most, and the time a manager is kept after its last request
+const (
+	maxManagers        = 1000
+	managerIdleTimeout = 10 * time.Minute
+)
+
+// tokenManager is the manager of a passthrough caller
+type tokenManager struct {
+	*rest.RestManager
+	lastUsed time.Time
+}
+
+// NewProxy returns a proxy sending requests with the token, or with the Authorization header of callers
+// in passthrough mode
+func NewProxy(token string, passthrough bool, options ...rest.Option) *Proxy {
+	return &Proxy{
+		Metrics:     NewMetrics(),
+		token:       token,
+		passthrough: passthrough,
+		options:     options,
+		managers:    make(map[[sha256.Size]byte]*tokenManager),
+	}
+}
+
+// manager returns the manager of an Authorization header. Rate limits are tracked per token, so every
+// token has its own manager in passthrough mode. The managers are keyed by the hash of the header, not to
+// keep tokens around, and are dropped once idle or when there are too many of them
+func (p *Proxy) manager(authorization string) *rest.RestManager {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.passthrough {
+		if p.shared == nil {
+			p.shared = p.newManager()
+		}
+		return p.shared
+	}
+
+	now := time.Now()
+	if now.Sub(p.lastSweep) > managerIdleTimeout {
+		p.lastSweep = now
+		for key, manager := range p.managers {
+			if now.Sub(manager.lastUsed) > managerIdleTimeout {
+				delete(p.managers, key)
+			}
+		}
+	}
+
+	key := sha256.Sum256([]byte(authorization))
+	manager, ok := p.managers[key]
+	if !ok {
+		if len(p.managers) >= maxManagers {
+			p.dropLeastRecentlyUsed()
+		}
+		manager = &tokenManager{RestManager: p.newManager()}
+		p.managers[key] = manager
+	}
+	manager.lastUsed = now
+
+	return manager.RestManager
+}
+
+func (p *Proxy) newManager() *rest.RestManager {
+	manager := rest.NewRestManager(p.token, p.options...)
+	manager.OnRateLimit = p.Metrics.rateLimited
+	return manager
+}
+
+// dropLeastRecentlyUsed drops the manager used the longest time ago, p.mu must be held
+func (p *Proxy) dropLeastRecentlyUsed() {
+	var oldest [sha256.Size]byte
+	var oldestUse time.Time
+	for key, manager := range p.managers {
+		if oldestUse.IsZero() || manager.lastUsed.Before(oldestUse) {
+			oldest, oldestUse = key, manager.lastUsed
+		}
+	}
+
+	delete(p.managers, oldest)
+}
+
+// validAuthorization returns whether an Authorization header holds a bot token or a bearer token
+func validAuthorization(authorization string) bool {
+	parts := strings.Split(authorization, " ")
+	if len(parts) != 2 || (parts[0] != "Bot" && parts[0] != "Bearer") || parts[1] == "" {
+		return false
+	}
+
+	for _, c := range parts[1] {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Handler returns the handler forwarding requests, and serving the metrics on /metrics
+func (p *Proxy) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", p.Metrics)
+	mux.Handle("/", p)
+
+	return mux
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	authorization := r.Header.Get("Authorization")
+	if p.passthrough && !validAuthorization(authorization) {
+		p.error(w, r, start, http.StatusUnauthorized, "the Authorization header must be Bot <token> or Bearer <token>")
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		p.error(w, r, start, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	header := r.Header.Clone()
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+	if !p.passthrough {
+		header.Del("Authorization")
+	}
+
+	// callers may keep the /api prefix of Discord's URLs
+	path := strings.TrimPrefix(r.URL.Path, "/api")
+	if r.URL.RawQuery != "" {
+		path += "?" + r.URL.RawQuery
+	}
+
+	resp, err := p.manager(authorization).Forward(r.Context(), r.Method, path, header, body)
+	if err != nil {
+		p.error(w, r, start, http.StatusBadGateway, err.Error())
+		return
+	}
+	defer resp.Body.Close()
+
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	for _, name := range hopHeaders {
+		w.Header().Del(name)
+	}
+	w.WriteHeader(resp.StatusCode)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		// the route is logged rather than the path, which may hold a webhook token
+		log.Printf("error while forwarding the response of %s: %s", rest.ParseRoute(r.Method, path), err)
+	}
+
+	p.Metrics.request(r.Method, resp.StatusCode, time.Since(start))
+}
+
+// error answers a request the proxy couldn't forward
+func (p *Proxy) error(w http.ResponseWriter, r *http.Request, start time.Time, status int, message string) {
+	http.Error(w, message, status)
+	p.Metrics.request(r.Method, status, time.Since(start))
+}
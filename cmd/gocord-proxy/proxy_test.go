@@ -0,0 +1,197 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Soumil07/gocord/rest"
+)
+
+// newTestUpstream returns a stand-in for Discord allowing 2 requests every 100ms, recording the requests
+// it receives
+func newTestUpstream(t *testing.T) (*httptest.Server, *[]*http.Request, *[]string) {
+	var mu sync.Mutex
+	var requests []*http.Request
+	var bodies []string
+	var reset time.Time
+	var remaining int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if now := time.Now(); now.After(reset) {
+			reset = now.Add(100 * time.Millisecond)
+			remaining = 2
+		}
+
+		h := w.Header()
+		h.Set("X-RateLimit-Bucket", "a")
+		h.Set("X-RateLimit-Limit", "2")
+		h.Set("X-RateLimit-Reset-After", strconv.FormatFloat(time.Until(reset).Seconds(), 'f', 3, 64))
+		if remaining == 0 {
+			h.Set("X-RateLimit-Remaining", "0")
+			h.Set("Retry-After", strconv.FormatFloat(time.Until(reset).Seconds(), 'f', 3, 64))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		remaining--
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+
+		h.Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": "1"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests, &bodies
+}
+
+func TestProxy(t *testing.T) {
+	t.Run("forwarding", func(t *testing.T) {
+		upstream, requests, bodies := newTestUpstream(t)
+		proxy := httptest.NewServer(NewProxy("token", false, rest.WithBaseURL(upstream.URL)))
+		defer proxy.Close()
+
+		req, _ := http.NewRequest(http.MethodPost, proxy.URL+"/api/v6/channels/1/messages?wait=true", strings.NewReader(`{"content": "hi"}`))
+		req.Header.Set("Authorization", "Bot someone-else")
+		req.Header.Set("X-Audit-Log-Reason", "testing")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK || string(body) != `{"id": "1"}` || resp.Header.Get("X-RateLimit-Bucket") != "a" {
+			t.Errorf("unexpected response: %d %s %v", resp.StatusCode, body, resp.Header)
+		}
+
+		received := (*requests)[0]
+		if received.Method != http.MethodPost || received.URL.Path != "/v6/channels/1/messages" || received.URL.RawQuery != "wait=true" {
+			t.Errorf("unexpected request: %s %s", received.Method, received.URL)
+		}
+		if received.Header.Get("Authorization") != "Bot token" || received.Header.Get("X-Audit-Log-Reason") != "testing" {
+			t.Errorf("unexpected headers: %v", received.Header)
+		}
+		if (*bodies)[0] != `{"content": "hi"}` {
+			t.Errorf("unexpected body: %s", (*bodies)[0])
+		}
+	})
+
+	t.Run("shared rate limits", func(t *testing.T) {
+		upstream, requests, _ := newTestUpstream(t)
+		server := httptest.NewServer(NewProxy("token", false, rest.WithBaseURL(upstream.URL)).Handler())
+		defer server.Close()
+
+		// every caller shares the limit of 2 requests every 100ms
+		var wg sync.WaitGroup
+		statuses := make(chan int, 6)
+		for i := 0; i < 6; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := http.Post(server.URL+"/v6/channels/1/messages", "application/json", strings.NewReader("{}"))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				resp.Body.Close()
+				statuses <- resp.StatusCode
+			}()
+		}
+		wg.Wait()
+		close(statuses)
+
+		for status := range statuses {
+			if status != http.StatusOK {
+				t.Errorf("unexpected status %d", status)
+			}
+		}
+		if len(*requests) != 6 {
+			t.Errorf("expected 6 requests, got %d", len(*requests))
+		}
+
+		resp, err := http.Get(server.URL + "/metrics")
+		if err != nil {
+			t.Fatal(err)
+		}
+		metrics, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.Contains(string(metrics), `gocord_proxy_requests_total{method="POST",status="200"} 6`) {
+			t.Errorf("unexpected metrics:\n%s", metrics)
+		}
+	})
+
+	t.Run("passthrough", func(t *testing.T) {
+		upstream, requests, _ := newTestUpstream(t)
+		proxy := httptest.NewServer(NewProxy("", true, rest.WithBaseURL(upstream.URL)))
+		defer proxy.Close()
+
+		for _, authorization := range []string{"", "Bot", "Bot ", "Basic dXNlcjpwYXNz", "Bot a b", "token"} {
+			req, _ := http.NewRequest(http.MethodGet, proxy.URL+"/v6/users/@me", nil)
+			if authorization != "" {
+				req.Header.Set("Authorization", authorization)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Errorf("expected 401 with the Authorization header %q, got %d", authorization, resp.StatusCode)
+			}
+		}
+		if len(*requests) != 0 {
+			t.Fatalf("%d requests with a malformed Authorization header were forwarded", len(*requests))
+		}
+
+		req, _ := http.NewRequest(http.MethodGet, proxy.URL+"/v6/users/@me", nil)
+		req.Header.Set("Authorization", "Bearer access")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if len(*requests) != 1 || (*requests)[0].Header.Get("Authorization") != "Bearer access" {
+			t.Errorf("the Authorization header wasn't passed through")
+		}
+	})
+
+	t.Run("passthrough managers", func(t *testing.T) {
+		proxy := NewProxy("", true)
+
+		first := proxy.manager("Bot first")
+		if proxy.manager("Bot first") != first || proxy.manager("Bot second") == first {
+			t.Error("managers aren't kept per token")
+		}
+
+		// idle managers are dropped
+		for _, manager := range proxy.managers {
+			manager.lastUsed = time.Now().Add(-2 * managerIdleTimeout)
+		}
+		proxy.lastSweep = time.Time{}
+		if proxy.manager("Bot first") == first || len(proxy.managers) != 1 {
+			t.Errorf("idle managers were kept, %d managers", len(proxy.managers))
+		}
+
+		// the least recently used manager makes room for new ones
+		for i := 0; i < maxManagers; i++ {
+			proxy.manager("Bot " + strconv.Itoa(i))
+		}
+		if len(proxy.managers) != maxManagers {
+			t.Errorf("expected %d managers, got %d", maxManagers, len(proxy.managers))
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package gocord
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// Implements zlib-stream transport compression, where the whole connection is a single zlib stream and every
+// payload ends with a Z_SYNC_FLUSH
+
+const (
+	// zlibHeaderSize is the size of the zlib header sent at the start of the stream
+	zlibHeaderSize = 2
+	// windowSize is the size of the deflate window, the furthest back a payload can reference
+	windowSize = 32 * 1024
+)
+
+// zlibSuffix is the Z_SYNC_FLUSH marker ending every compressed payload
+var zlibSuffix = []byte{0x00, 0x00, 0xff, 0xff}
+
+var errZlibHeader = errors.New("invalid zlib-stream header")
+
+// inflater decompresses a zlib-stream, keeping the compression context across payloads. Every connection
+// starts a new stream, and needs a new inflater
+type inflater struct {
+	buf     bytes.Buffer // compressed data received since the last complete payload
+	history []byte       // the last 32KB decompressed, the dictionary of the next payload
+	reader  io.ReadCloser
+}
+
+// Write buffers a frame, returning the decompressed payload once a frame ends with Z_SYNC_FLUSH.
+// nil is returned for frames holding partial payloads
+func (z *inflater) Write(frame []byte) ([]byte, error) {
+	z.buf.Write(frame)
+	if !bytes.HasSuffix(frame, zlibSuffix) {
+		return nil, nil
+	}
+
+	if z.reader == nil {
+		header := z.buf.Next(zlibHeaderSize)
+		if len(header) != zlibHeaderSize || header[0]&0x0f != 8 {
+			return nil, errZlibHeader
+		}
+		z.reader = flate.NewReader(nil)
+	}
+
+	// the flate reader can't wait for more data, so every payload is decompressed with a fresh reader using
+	// the previous output as its dictionary. A sync flush doesn't end the stream, so reading stops with an
+	// unexpected EOF once the payload is decompressed
+	err := z.reader.(flate.Resetter).Reset(&z.buf, z.history)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := ioutil.ReadAll(z.reader)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	z.buf.Reset()
+
+	z.history = append(z.history, payload...)
+	if len(z.history) > windowSize {
+		z.history = append(z.history[:0], z.history[len(z.history)-windowSize:]...)
+	}
+
+	return payload, nil
+}
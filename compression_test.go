@@ -0,0 +1,52 @@
+package gocord
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// TestInflater replays frames recorded from a zlib-stream, split as Discord splits large payloads
+func TestInflater(t *testing.T) {
+	recorded, err := ioutil.ReadFile("testdata/zlib-stream.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := os.Open("testdata/zlib-stream.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer expected.Close()
+	lines := bufio.NewScanner(expected)
+	lines.Buffer(nil, 1024*1024)
+
+	z := &inflater{}
+	for len(recorded) > 0 {
+		size := binary.BigEndian.Uint32(recorded)
+		frame := recorded[4 : 4+size]
+		recorded = recorded[4+size:]
+
+		payload, err := z.Write(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if payload == nil {
+			continue
+		}
+
+		if !lines.Scan() {
+			t.Fatalf("unexpected payload: %s", payload)
+		}
+		if !bytes.Equal(payload, lines.Bytes()) {
+			t.Errorf("expected %s, received %s", lines.Bytes(), payload)
+		}
+	}
+
+	if lines.Scan() {
+		t.Errorf("payload not decompressed: %s", lines.Bytes())
+	}
+}
@@ -23,10 +23,76 @@ const (
 const (
 	// ReadyEvent is the first event sent by Discord, including the session ID, guilds and other information
 	ReadyEvent = "READY"
+	// ResumedEvent is sent once a resumed session has replayed all missed events
+	ResumedEvent = "RESUMED"
 	// GuildCreateEvent is dispatched to lazy load a guild, or when a new guild is added
 	GuildCreateEvent = "GUILD_CREATE"
+	// GuildUpdateEvent is dispatched when a guild is updated
+	GuildUpdateEvent = "GUILD_UPDATE"
+	// GuildDeleteEvent is dispatched when a guild becomes unavailable, or the user leaves or is removed from it
+	GuildDeleteEvent = "GUILD_DELETE"
+	// GuildBanAddEvent is dispatched when a user is banned from a guild
+	GuildBanAddEvent = "GUILD_BAN_ADD"
+	// GuildBanRemoveEvent is dispatched when a user is unbanned from a guild
+	GuildBanRemoveEvent = "GUILD_BAN_REMOVE"
+	// GuildEmojisUpdateEvent is dispatched when the emojis of a guild are updated
+	GuildEmojisUpdateEvent = "GUILD_EMOJIS_UPDATE"
+	// GuildIntegrationsUpdateEvent is dispatched when the integrations of a guild are updated
+	GuildIntegrationsUpdateEvent = "GUILD_INTEGRATIONS_UPDATE"
+	// GuildMemberAddEvent is dispatched when a user joins a guild
+	GuildMemberAddEvent = "GUILD_MEMBER_ADD"
+	// GuildMemberUpdateEvent is dispatched when a guild member is updated
+	GuildMemberUpdateEvent = "GUILD_MEMBER_UPDATE"
+	// GuildMemberRemoveEvent is dispatched when a user leaves or is removed from a guild
+	GuildMemberRemoveEvent = "GUILD_MEMBER_REMOVE"
+	// GuildMembersChunkEvent is dispatched in response to a request guild members payload
+	GuildMembersChunkEvent = "GUILD_MEMBERS_CHUNK"
+	// GuildRoleCreateEvent is dispatched when a role is created
+	GuildRoleCreateEvent = "GUILD_ROLE_CREATE"
+	// GuildRoleUpdateEvent is dispatched when a role is updated
+	GuildRoleUpdateEvent = "GUILD_ROLE_UPDATE"
+	// GuildRoleDeleteEvent is dispatched when a role is deleted
+	GuildRoleDeleteEvent = "GUILD_ROLE_DELETE"
+	// ChannelCreateEvent is dispatched when a channel is created
+	ChannelCreateEvent = "CHANNEL_CREATE"
+	// ChannelUpdateEvent is dispatched when a channel is updated
+	ChannelUpdateEvent = "CHANNEL_UPDATE"
+	// ChannelDeleteEvent is dispatched when a channel is deleted
+	ChannelDeleteEvent = "CHANNEL_DELETE"
+	// ChannelPinsUpdateEvent is dispatched when a message is pinned or unpinned
+	ChannelPinsUpdateEvent = "CHANNEL_PINS_UPDATE"
+	// InviteCreateEvent is dispatched when an invite is created
+	InviteCreateEvent = "INVITE_CREATE"
+	// InviteDeleteEvent is dispatched when an invite is deleted
+	InviteDeleteEvent = "INVITE_DELETE"
 	// MessageEvent is dispatched when a message is sent
 	MessageEvent = "MESSAGE_CREATE"
+	// MessageUpdateEvent is dispatched when a message is edited
+	MessageUpdateEvent = "MESSAGE_UPDATE"
+	// MessageDeleteEvent is dispatched when a message is deleted
+	MessageDeleteEvent = "MESSAGE_DELETE"
+	// MessageDeleteBulkEvent is dispatched when messages are bulk deleted
+	MessageDeleteBulkEvent = "MESSAGE_DELETE_BULK"
+	// MessageReactionAddEvent is dispatched when a user reacts to a message
+	MessageReactionAddEvent = "MESSAGE_REACTION_ADD"
+	// MessageReactionRemoveEvent is dispatched when a user removes a reaction from a message
+	MessageReactionRemoveEvent = "MESSAGE_REACTION_REMOVE"
+	// MessageReactionRemoveAllEvent is dispatched when all reactions are removed from a message
+	MessageReactionRemoveAllEvent = "MESSAGE_REACTION_REMOVE_ALL"
+	// MessageReactionRemoveEmojiEvent is dispatched when all reactions of an emoji are removed from a message
+	MessageReactionRemoveEmojiEvent = "MESSAGE_REACTION_REMOVE_EMOJI"
+	// PresenceUpdateEvent is dispatched when the presence of a guild member is updated
+	PresenceUpdateEvent = "PRESENCE_UPDATE"
+	// TypingStartEvent is dispatched when a user starts typing in a channel
+	TypingStartEvent = "TYPING_START"
+	// UserUpdateEvent is dispatched when the current user is updated
+	UserUpdateEvent = "USER_UPDATE"
+	// VoiceStateUpdateEvent is dispatched when a user joins, leaves or moves between voice channels
+	VoiceStateUpdateEvent = "VOICE_STATE_UPDATE"
+	// VoiceServerUpdateEvent is dispatched when the voice server of a guild is assigned or changed
+	VoiceServerUpdateEvent = "VOICE_SERVER_UPDATE"
+	// WebhooksUpdateEvent is dispatched when the webhooks of a channel are updated
+	WebhooksUpdateEvent = "WEBHOOKS_UPDATE"
 )
 
 const (
@@ -51,7 +117,11 @@ const (
 const (
 	// APIVersion is the current usable discord API version
 	APIVersion = 6
-	// RestURL is the base URL for all rest api requests
+	// RestURL is the base URL rest api requests were sent to
+	//
+	// Deprecated: requests are sent to the base URL configured through ClusterOptions.Rest, with the API
+	// version of the rest manager. The gateway, fetched from v7 at this URL, is now fetched from v6 like
+	// every other request unless rest.WithAPIVersion is passed
 	RestURL     = "https://discordapp.com/api/v7"
 	CdnUrl      = "https://cdn.discordapp.com"
 	gatewayPath = "/gateway/bot"
@@ -0,0 +1,65 @@
+package gocord
+
+import (
+	"encoding/json"
+
+	"github.com/Soumil07/gocord/etf"
+	"github.com/gorilla/websocket"
+)
+
+// Contains the encodings payloads are exchanged with the gateway in
+
+// Encoding encodes payloads sent to the gateway, and decodes payloads received from it
+type Encoding interface {
+	// Name is the name of the encoding in the gateway URL
+	Name() string
+	// MessageType is the websocket message type payloads are sent with
+	MessageType() int
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	// JSONEncoding exchanges payloads as JSON, the default encoding
+	JSONEncoding Encoding = jsonEncoding{}
+	// ETFEncoding exchanges payloads as Erlang Term Format, which is smaller and cheaper for Discord to send
+	ETFEncoding Encoding = etfEncoding{}
+)
+
+type jsonEncoding struct{}
+
+func (jsonEncoding) Name() string {
+	return "json"
+}
+
+func (jsonEncoding) MessageType() int {
+	return websocket.TextMessage
+}
+
+func (jsonEncoding) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonEncoding) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// etfEncoding decodes received terms straight into the event structs following their json tags. Snowflakes,
+// sent as integers, are decoded into string fields as strings
+type etfEncoding struct{}
+
+func (etfEncoding) Name() string {
+	return "etf"
+}
+
+func (etfEncoding) MessageType() int {
+	return websocket.BinaryMessage
+}
+
+func (etfEncoding) Marshal(v interface{}) ([]byte, error) {
+	return etf.Marshal(v)
+}
+
+func (etfEncoding) Unmarshal(data []byte, v interface{}) error {
+	return etf.Unmarshal(data, v)
+}
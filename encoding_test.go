@@ -0,0 +1,39 @@
+package gocord
+
+import (
+	"testing"
+
+	"github.com/Soumil07/gocord/etf"
+)
+
+func TestETFEncoding(t *testing.T) {
+	data, err := etf.Marshal(map[string]interface{}{
+		"op": OPCodeDispatch,
+		"s":  nil,
+		"t":  MessageDeleteEvent,
+		"d": map[string]interface{}{
+			"id":         int64(532935925194555392),
+			"channel_id": int64(372539957824323584),
+			"guild_id":   int64(81), // small snowflakes are sent as small integers
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var payload receivePayload
+	if err = ETFEncoding.Unmarshal(data, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.OP != OPCodeDispatch || payload.T != MessageDeleteEvent || payload.Seq != 0 {
+		t.Errorf("unexpected payload: %#v", payload)
+	}
+
+	var m MessageDelete
+	if err = ETFEncoding.Unmarshal(payload.D, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != "532935925194555392" || m.ChannelID != "372539957824323584" || m.GuildID != "81" {
+		t.Errorf("snowflakes were not decoded: %#v", m)
+	}
+}
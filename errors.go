@@ -0,0 +1,142 @@
+package gocord
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gorilla/websocket"
+)
+
+// Contains gateway close codes, and the errors and reconnect actions they map to
+
+// Gateway close codes, as documented at https://discordapp.com/developers/docs/topics/opcodes-and-status-codes#gateway-close-event-codes
+const (
+	CloseUnknownError         = 4000
+	CloseUnknownOPCode        = 4001
+	CloseDecodeError          = 4002
+	CloseNotAuthenticated     = 4003
+	CloseAuthenticationFailed = 4004
+	CloseAlreadyAuthenticated = 4005
+	CloseInvalidSeq           = 4007
+	CloseRateLimited          = 4008
+	CloseSessionTimedOut      = 4009
+	CloseInvalidShard         = 4010
+	CloseShardingRequired     = 4011
+	CloseInvalidAPIVersion    = 4012
+	CloseInvalidIntents       = 4013
+	CloseDisallowedIntents    = 4014
+)
+
+// Errors returned for each gateway close code. Use errors.Is to check the error a shard disconnected with
+var (
+	ErrUnknownError         = errors.New("unknown gateway error")
+	ErrUnknownOPCode        = errors.New("invalid opcode or payload sent")
+	ErrDecodeError          = errors.New("invalid payload sent")
+	ErrNotAuthenticated     = errors.New("payload sent before identifying")
+	ErrAuthenticationFailed = errors.New("authentication failed, the token is invalid")
+	ErrAlreadyAuthenticated = errors.New("identify sent more than once")
+	ErrInvalidSeq           = errors.New("invalid sequence sent while resuming")
+	ErrRateLimited          = errors.New("payloads sent too quickly")
+	ErrSessionTimedOut      = errors.New("session timed out")
+	ErrInvalidShard         = errors.New("invalid shard sent while identifying")
+	ErrShardingRequired     = errors.New("the session would handle too many guilds, more shards are required")
+	ErrInvalidAPIVersion    = errors.New("invalid gateway API version")
+	ErrInvalidIntents       = errors.New("invalid intents sent while identifying")
+	ErrDisallowedIntents    = errors.New("disallowed intents sent while identifying, they may not be enabled for the application")
+
+	// ErrReconnectRequested is returned when the gateway asks the shard to reconnect
+	ErrReconnectRequested = errors.New("the gateway requested a reconnect")
+)
+
+// ReconnectAction is the action taken by a shard after it disconnects
+type ReconnectAction int
+
+const (
+	// ReconnectResume reconnects and resumes the current session
+	ReconnectResume ReconnectAction = iota
+	// ReconnectIdentify reconnects and starts a new session
+	ReconnectIdentify
+	// ReconnectStop stops the shard permanently, reconnecting would fail again
+	ReconnectStop
+)
+
+func (a ReconnectAction) String() string {
+	switch a {
+	case ReconnectResume:
+		return "resume"
+	case ReconnectIdentify:
+		return "identify"
+	case ReconnectStop:
+		return "stop"
+	}
+
+	return fmt.Sprintf("ReconnectAction(%d)", int(a))
+}
+
+type closeCode struct {
+	err    error
+	action ReconnectAction
+}
+
+var closeCodes = map[int]closeCode{
+	CloseUnknownError:         {ErrUnknownError, ReconnectResume},
+	CloseUnknownOPCode:        {ErrUnknownOPCode, ReconnectResume},
+	CloseDecodeError:          {ErrDecodeError, ReconnectResume},
+	CloseNotAuthenticated:     {ErrNotAuthenticated, ReconnectIdentify},
+	CloseAuthenticationFailed: {ErrAuthenticationFailed, ReconnectStop},
+	CloseAlreadyAuthenticated: {ErrAlreadyAuthenticated, ReconnectResume},
+	CloseInvalidSeq:           {ErrInvalidSeq, ReconnectIdentify},
+	CloseRateLimited:          {ErrRateLimited, ReconnectResume},
+	CloseSessionTimedOut:      {ErrSessionTimedOut, ReconnectIdentify},
+	CloseInvalidShard:         {ErrInvalidShard, ReconnectStop},
+	CloseShardingRequired:     {ErrShardingRequired, ReconnectStop},
+	CloseInvalidAPIVersion:    {ErrInvalidAPIVersion, ReconnectStop},
+	CloseInvalidIntents:       {ErrInvalidIntents, ReconnectStop},
+	CloseDisallowedIntents:    {ErrDisallowedIntents, ReconnectStop},
+}
+
+// CloseError is the error a shard disconnects with when the gateway closes the connection
+type CloseError struct {
+	Code   int    // the close code sent by the gateway
+	Reason string // the close reason sent by the gateway
+	Err    error  // one of the Err* errors mapped to the close code, nil for unknown codes
+}
+
+func (e *CloseError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("gateway closed with code %d: %s", e.Code, e.Err.Error())
+	}
+
+	return fmt.Sprintf("gateway closed with code %d: %s", e.Code, e.Reason)
+}
+
+func (e *CloseError) Unwrap() error {
+	return e.Err
+}
+
+// ShardDisconnect is dispatched to the cluster as "shardDisconnect" whenever a shard loses its connection
+type ShardDisconnect struct {
+	Err    error           // the reason the shard disconnected
+	Action ReconnectAction // what the shard does next
+}
+
+// disconnectReason maps the error a connection ended with to the error reported to the cluster and the
+// action the shard should take
+func disconnectReason(err error) (error, ReconnectAction) {
+	var wsErr *websocket.CloseError
+	if !errors.As(err, &wsErr) {
+		// network errors, zombie connections and requested reconnects
+		return err, ReconnectResume
+	}
+
+	closeErr := &CloseError{
+		Code:   wsErr.Code,
+		Reason: wsErr.Text,
+	}
+	if code, ok := closeCodes[wsErr.Code]; ok {
+		closeErr.Err = code.err
+		return closeErr, code.action
+	}
+
+	return closeErr, ReconnectResume
+}
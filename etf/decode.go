@@ -0,0 +1,546 @@
+package etf
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Unmarshaler is implemented by types decoding their term themselves. UnmarshalETF is given the whole term,
+// starting with the version, so it may be decoded later with Unmarshal
+type Unmarshaler interface {
+	UnmarshalETF(data []byte) error
+}
+
+// Unmarshal decodes a term into the value pointed to by v, without going through JSON. Maps are decoded
+// into structs following their json tags, the keys matching the field names case insensitively when no
+// field has the exact name. Integers are decoded into string fields as decimal strings, and binaries into
+// integer fields are parsed, so snowflakes are decoded into either depending on the field.
+//
+// The nil atom sets pointers, maps, slices and interfaces to nil and leaves other values unchanged. Terms
+// decoded into an interface{} are decoded as nil, bools, int64 or *big.Int, float64, strings, []interface{}
+// and map[string]interface{}
+func Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("etf: Unmarshal requires a non-nil pointer")
+	}
+
+	d, err := newDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	return d.value(rv.Elem())
+}
+
+func typeError(term string, t reflect.Type) error {
+	return fmt.Errorf("etf: cannot decode %s into %s", term, t)
+}
+
+func (d *decoder) value(v reflect.Value) error {
+	if v.Kind() != reflect.Ptr && v.CanAddr() {
+		if u, ok := v.Addr().Interface().(Unmarshaler); ok {
+			start := d.pos
+			if err := d.skip(); err != nil {
+				return err
+			}
+
+			term := make([]byte, 0, 1+d.pos-start)
+			term = append(append(term, version), d.data[start:d.pos]...)
+			return u.UnmarshalETF(term)
+		}
+	}
+
+	if d.nilAtom() {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return d.skip()
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.value(v.Elem())
+	}
+
+	if v.Kind() == reflect.Interface {
+		if v.NumMethod() > 0 {
+			return typeError("a term", v.Type())
+		}
+		t, err := d.term()
+		if err != nil {
+			return err
+		}
+		if t != nil {
+			v.Set(reflect.ValueOf(t))
+		}
+		return nil
+	}
+
+	b, err := d.next(1)
+	if err != nil {
+		return err
+	}
+	tag := b[0]
+
+	switch tag {
+	case smallIntegerExt, integerExt, smallBigExt, largeBigExt:
+		i, large, err := d.integer(tag)
+		if err != nil {
+			return err
+		}
+		return setInteger(v, i, large)
+
+	case newFloatExt:
+		f, err := d.float()
+		if err != nil {
+			return err
+		}
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			v.SetFloat(f)
+			return nil
+		}
+		return typeError("a float", v.Type())
+
+	case atomExt, smallAtomExt, atomUTF8Ext, smallAtomUTF8Ext:
+		atom, err := d.bytes(tag)
+		if err != nil {
+			return err
+		}
+
+		switch v.Kind() {
+		case reflect.Bool:
+			if s := string(atom); s == "true" || s == "false" {
+				v.SetBool(s == "true")
+				return nil
+			}
+		case reflect.String:
+			v.SetString(string(atom))
+			return nil
+		}
+		return typeError("the atom "+string(atom), v.Type())
+
+	case binaryExt, stringExt:
+		s, err := d.bytes(tag)
+		if err != nil {
+			return err
+		}
+
+		// strings are lists of small integers
+		if tag == stringExt && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+			return setList(v, len(s), func(e reflect.Value, i int) error { return setInteger(e, int64(s[i]), nil) })
+		}
+		return setBinary(v, s)
+
+	case nilExt:
+		switch v.Kind() {
+		case reflect.Slice:
+			v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+			return nil
+		case reflect.Array:
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		case reflect.String:
+			v.SetString("")
+			return nil
+		}
+		return typeError("an empty list", v.Type())
+
+	case listExt, smallTupleExt, largeTupleExt:
+		n, err := d.count(tag)
+		if err != nil {
+			return err
+		}
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return typeError("a list", v.Type())
+		}
+
+		if err = setList(v, n, func(e reflect.Value, _ int) error { return d.value(e) }); err != nil {
+			return err
+		}
+		if tag == listExt {
+			return d.tail()
+		}
+		return nil
+
+	case mapExt:
+		n, err := d.count(tag)
+		if err != nil {
+			return err
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			return d.structFields(v, n)
+		case reflect.Map:
+			return d.mapEntries(v, n)
+		}
+		return typeError("a map", v.Type())
+	}
+
+	return fmt.Errorf("etf: unsupported tag %d", tag)
+}
+
+// setInteger sets an integer to a numeric or string value, snowflakes being decoded as decimal strings
+func setInteger(v reflect.Value, i int64, large *big.Int) error {
+	switch v.Kind() {
+	case reflect.String:
+		if large != nil {
+			v.SetString(large.String())
+		} else {
+			v.SetString(strconv.FormatInt(i, 10))
+		}
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if large == nil && !v.OverflowInt(i) {
+			v.SetInt(i)
+			return nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if large != nil && large.IsUint64() && !v.OverflowUint(large.Uint64()) {
+			v.SetUint(large.Uint64())
+			return nil
+		}
+		if large == nil && i >= 0 && !v.OverflowUint(uint64(i)) {
+			v.SetUint(uint64(i))
+			return nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if large != nil {
+			f, _ := new(big.Float).SetInt(large).Float64()
+			v.SetFloat(f)
+		} else {
+			v.SetFloat(float64(i))
+		}
+		return nil
+	}
+
+	if large != nil {
+		return typeError("the integer "+large.String(), v.Type())
+	}
+	return typeError("the integer "+strconv.FormatInt(i, 10), v.Type())
+}
+
+// setBinary sets a binary to a string, a byte slice or an integer, snowflakes sent as strings being parsed
+func setBinary(v reflect.Value, s []byte) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(string(s))
+		return nil
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes(append([]byte(nil), s...))
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(string(s), 10, 64)
+		if err == nil && !v.OverflowInt(i) {
+			v.SetInt(i)
+			return nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(string(s), 10, 64)
+		if err == nil && !v.OverflowUint(u) {
+			v.SetUint(u)
+			return nil
+		}
+	}
+
+	return typeError(strconv.Quote(string(s)), v.Type())
+}
+
+// setList decodes n elements into a slice or an array, elements past the end of an array being skipped
+func setList(v reflect.Value, n int, decode func(e reflect.Value, i int) error) error {
+	if v.Kind() == reflect.Slice {
+		v.Set(reflect.MakeSlice(v.Type(), n, n))
+	}
+
+	var skipped reflect.Value
+	for i := 0; i < n; i++ {
+		var e reflect.Value
+		if i < v.Len() {
+			e = v.Index(i)
+		} else {
+			if !skipped.IsValid() {
+				skipped = reflect.New(v.Type().Elem()).Elem()
+			}
+			e = skipped
+		}
+
+		if err := decode(e, i); err != nil {
+			return err
+		}
+	}
+
+	// the remaining elements of an array are zeroed
+	for i := n; v.Kind() == reflect.Array && i < v.Len(); i++ {
+		v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+	}
+	return nil
+}
+
+func (d *decoder) structFields(v reflect.Value, n int) error {
+	fields := cachedFields(v.Type())
+
+	for i := 0; i < n; i++ {
+		key, err := d.key()
+		if err != nil {
+			return err
+		}
+
+		f, ok := fields.lookup(key)
+		if !ok {
+			if err = d.skip(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err = d.value(fieldByIndex(v, f.index)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *decoder) mapEntries(v reflect.Value, n int) error {
+	t := v.Type()
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(t, n))
+	}
+
+	for i := 0; i < n; i++ {
+		key, err := d.key()
+		if err != nil {
+			return err
+		}
+
+		k := reflect.New(t.Key()).Elem()
+		switch t.Key().Kind() {
+		case reflect.String:
+			k.SetString(key)
+		default:
+			if err = setBinary(k, []byte(key)); err != nil {
+				return err
+			}
+		}
+
+		e := reflect.New(t.Elem()).Elem()
+		if err = d.value(e); err != nil {
+			return err
+		}
+		v.SetMapIndex(k, e)
+	}
+
+	return nil
+}
+
+// key reads the key of a map as a string, atoms and binaries being the usual keys
+func (d *decoder) key() (string, error) {
+	if d.pos >= len(d.data) {
+		return "", ErrTruncated
+	}
+
+	switch tag := d.data[d.pos]; tag {
+	case atomExt, smallAtomExt, atomUTF8Ext, smallAtomUTF8Ext, binaryExt, stringExt:
+		d.pos++
+		b, err := d.bytes(tag)
+		return string(b), err
+	}
+
+	k, err := d.term()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(k), nil
+}
+
+// nilAtom reports whether the next term is the nil atom
+func (d *decoder) nilAtom() bool {
+	rest := d.data[d.pos:]
+
+	switch {
+	case len(rest) >= 5 && (rest[0] == smallAtomExt || rest[0] == smallAtomUTF8Ext):
+		return rest[1] == 3 && string(rest[2:5]) == "nil"
+	case len(rest) >= 6 && (rest[0] == atomExt || rest[0] == atomUTF8Ext):
+		return rest[1] == 0 && rest[2] == 3 && string(rest[3:6]) == "nil"
+	}
+
+	return false
+}
+
+// skip reads a term without decoding it
+func (d *decoder) skip() error {
+	b, err := d.next(1)
+	if err != nil {
+		return err
+	}
+	tag := b[0]
+
+	switch tag {
+	case smallIntegerExt:
+		_, err = d.next(1)
+	case integerExt:
+		_, err = d.next(4)
+	case newFloatExt:
+		_, err = d.next(8)
+	case smallBigExt, largeBigExt:
+		var n int
+		if n, err = d.length(tag); err == nil {
+			_, err = d.next(n + 1)
+		}
+	case atomExt, smallAtomExt, atomUTF8Ext, smallAtomUTF8Ext, binaryExt, stringExt:
+		_, err = d.bytes(tag)
+	case nilExt:
+	case listExt, smallTupleExt, largeTupleExt, mapExt:
+		var n int
+		if n, err = d.length(tag); err != nil {
+			return err
+		}
+		if tag == mapExt {
+			n *= 2
+		}
+		for i := 0; i < n; i++ {
+			if err = d.skip(); err != nil {
+				return err
+			}
+		}
+		if tag == listExt {
+			err = d.tail()
+		}
+	default:
+		err = fmt.Errorf("etf: unsupported tag %d", tag)
+	}
+
+	return err
+}
+
+// field is a struct field decoded from a map, index being its path through embedded structs
+type field struct {
+	name  string
+	index []int
+}
+
+type structFields struct {
+	list   []field
+	byName map[string]int
+}
+
+// lookup finds the field of a key, matching its name case insensitively when no name is equal to it
+func (s *structFields) lookup(key string) (field, bool) {
+	if i, ok := s.byName[key]; ok {
+		return s.list[i], true
+	}
+	for _, f := range s.list {
+		if strings.EqualFold(f.name, key) {
+			return f, true
+		}
+	}
+
+	return field{}, false
+}
+
+var fieldCache sync.Map // reflect.Type to *structFields
+
+func cachedFields(t reflect.Type) *structFields {
+	if s, ok := fieldCache.Load(t); ok {
+		return s.(*structFields)
+	}
+
+	s, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return s.(*structFields)
+}
+
+// typeFields lists the fields of a struct by their JSON name, the fields of embedded structs being
+// promoted unless a shallower field has the same name
+func typeFields(t reflect.Type) *structFields {
+	s := &structFields{byName: make(map[string]int)}
+
+	type embedded struct {
+		t     reflect.Type
+		index []int
+	}
+	current := []embedded{{t: t}}
+	visited := make(map[reflect.Type]bool)
+
+	for len(current) > 0 {
+		var next []embedded
+
+		for _, e := range current {
+			if visited[e.t] {
+				continue
+			}
+			visited[e.t] = true
+
+			for i := 0; i < e.t.NumField(); i++ {
+				sf := e.t.Field(i)
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name := strings.Split(tag, ",")[0]
+				index := append(append([]int(nil), e.index...), i)
+
+				if sf.Anonymous && name == "" {
+					ft := sf.Type
+					if ft.Kind() == reflect.Ptr {
+						if sf.PkgPath != "" {
+							continue
+						}
+						ft = ft.Elem()
+					}
+					if ft.Kind() == reflect.Struct {
+						next = append(next, embedded{ft, index})
+						continue
+					}
+				}
+				if sf.PkgPath != "" {
+					continue
+				}
+
+				if name == "" {
+					name = sf.Name
+				}
+				if _, ok := s.byName[name]; !ok {
+					s.byName[name] = len(s.list)
+					s.list = append(s.list, field{name, index})
+				}
+			}
+		}
+
+		current = next
+	}
+
+	return s
+}
+
+// fieldByIndex returns a field through embedded structs, allocating nil embedded pointers
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+
+	return v
+}
@@ -0,0 +1,150 @@
+package etf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Marshal encodes a value as a term. nil is encoded as the nil atom, strings as binaries and integers too
+// large for 32 bits as big integers. Structs are encoded as maps following their JSON encoding
+func Marshal(v interface{}) ([]byte, error) {
+	out := []byte{version}
+	return appendTerm(out, v)
+}
+
+func appendTerm(out []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return appendAtom(out, "nil"), nil
+	case bool:
+		if v {
+			return appendAtom(out, "true"), nil
+		}
+		return appendAtom(out, "false"), nil
+	case string:
+		out = append(out, binaryExt)
+		out = appendUint32(out, uint32(len(v)))
+		return append(out, v...), nil
+	case []byte:
+		out = append(out, binaryExt)
+		out = appendUint32(out, uint32(len(v)))
+		return append(out, v...), nil
+	case int:
+		return appendInt(out, int64(v)), nil
+	case int32:
+		return appendInt(out, int64(v)), nil
+	case int64:
+		return appendInt(out, v), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return appendBig(out, 0, v), nil
+		}
+		return appendInt(out, int64(v)), nil
+	case float64:
+		out = append(out, newFloatExt)
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, math.Float64bits(v))
+		return append(out, b...), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return appendInt(out, i), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return appendTerm(out, f)
+	case []interface{}:
+		if len(v) == 0 {
+			return append(out, nilExt), nil
+		}
+		out = append(out, listExt)
+		out = appendUint32(out, uint32(len(v)))
+		for _, e := range v {
+			var err error
+			out, err = appendTerm(out, e)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(out, nilExt), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		out = append(out, mapExt)
+		out = appendUint32(out, uint32(len(v)))
+		for _, k := range keys {
+			var err error
+			out, err = appendTerm(out, k)
+			if err != nil {
+				return nil, err
+			}
+			out, err = appendTerm(out, v[k])
+			if err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+
+	// anything else, such as structs, is encoded following its JSON encoding
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("etf: %s", err.Error())
+	}
+
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	err = decoder.Decode(&generic)
+	if err != nil {
+		return nil, err
+	}
+
+	return appendTerm(out, generic)
+}
+
+func appendAtom(out []byte, atom string) []byte {
+	out = append(out, smallAtomUTF8Ext, byte(len(atom)))
+	return append(out, atom...)
+}
+
+func appendInt(out []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i <= math.MaxUint8:
+		return append(out, smallIntegerExt, byte(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		out = append(out, integerExt)
+		return appendUint32(out, uint32(int32(i)))
+	case i < 0:
+		return appendBig(out, 1, uint64(-i))
+	}
+
+	return appendBig(out, 0, uint64(i))
+}
+
+// appendBig writes a SMALL_BIG_EXT, digits being stored in little endian
+func appendBig(out []byte, sign byte, u uint64) []byte {
+	var digits []byte
+	for u > 0 {
+		digits = append(digits, byte(u))
+		u >>= 8
+	}
+
+	out = append(out, smallBigExt, byte(len(digits)), sign)
+	return append(out, digits...)
+}
+
+func appendUint32(out []byte, u uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, u)
+	return append(out, b...)
+}
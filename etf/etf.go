@@ -0,0 +1,299 @@
+// Package etf implements the subset of the Erlang External Term Format used by the Discord gateway
+package etf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// term tags, as documented at http://erlang.org/doc/apps/erts/erl_ext_dist.html
+const (
+	version = 131
+
+	newFloatExt      = 70
+	smallIntegerExt  = 97
+	integerExt       = 98
+	atomExt          = 100
+	smallTupleExt    = 104
+	largeTupleExt    = 105
+	nilExt           = 106
+	stringExt        = 107
+	listExt          = 108
+	binaryExt        = 109
+	smallBigExt      = 110
+	largeBigExt      = 111
+	smallAtomExt     = 115
+	mapExt           = 116
+	atomUTF8Ext      = 118
+	smallAtomUTF8Ext = 119
+)
+
+var (
+	// ErrVersion is returned when the data doesn't start with the external term format version
+	ErrVersion = errors.New("etf: invalid version")
+	// ErrTruncated is returned when the data ends in the middle of a term
+	ErrTruncated = errors.New("etf: unexpected end of data")
+)
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func newDecoder(data []byte) (*decoder, error) {
+	if len(data) == 0 || data[0] != version {
+		return nil, ErrVersion
+	}
+
+	return &decoder{data: data, pos: 1}, nil
+}
+
+func (d *decoder) next(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.data) {
+		return nil, ErrTruncated
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+
+	return b, nil
+}
+
+func (d *decoder) uint8() (int, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(b[0]), nil
+}
+
+func (d *decoder) uint16() (int, error) {
+	b, err := d.next(2)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(binary.BigEndian.Uint16(b)), nil
+}
+
+func (d *decoder) uint32() (int, error) {
+	b, err := d.next(4)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(binary.BigEndian.Uint32(b)), nil
+}
+
+// length reads the size of a term, the number of bytes read for the size depending on the tag
+func (d *decoder) length(tag byte) (int, error) {
+	switch tag {
+	case smallAtomExt, smallAtomUTF8Ext, smallTupleExt, smallBigExt:
+		return d.uint8()
+	case atomExt, atomUTF8Ext, stringExt:
+		return d.uint16()
+	}
+
+	return d.uint32()
+}
+
+// count reads the number of elements of a list, a tuple or a map. Every element takes at least a byte, so
+// counts the remaining data can't hold are rejected before anything is allocated for them
+func (d *decoder) count(tag byte) (int, error) {
+	n, err := d.length(tag)
+	if err != nil {
+		return 0, err
+	}
+
+	size := 1
+	if tag == mapExt {
+		size = 2 // a key and a value
+	}
+	if n < 0 || n > (len(d.data)-d.pos)/size {
+		return 0, ErrTruncated
+	}
+
+	return n, nil
+}
+
+// bigInt reads a SMALL_BIG_EXT or LARGE_BIG_EXT, digits being stored in little endian
+func (d *decoder) bigInt(tag byte) (int64, *big.Int, error) {
+	n, err := d.length(tag)
+	if err != nil {
+		return 0, nil, err
+	}
+	sign, err := d.uint8()
+	if err != nil {
+		return 0, nil, err
+	}
+	digits, err := d.next(n)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if n <= 8 {
+		var u uint64
+		for i := n - 1; i >= 0; i-- {
+			u = u<<8 | uint64(digits[i])
+		}
+		if u <= math.MaxInt64 {
+			if sign != 0 {
+				return -int64(u), nil, nil
+			}
+			return int64(u), nil, nil
+		}
+	}
+
+	bigEndian := make([]byte, n)
+	for i, digit := range digits {
+		bigEndian[n-1-i] = digit
+	}
+	i := new(big.Int).SetBytes(bigEndian)
+	if sign != 0 {
+		i.Neg(i)
+	}
+
+	return 0, i, nil
+}
+
+// integer reads any integer term, large integers being returned as a *big.Int
+func (d *decoder) integer(tag byte) (int64, *big.Int, error) {
+	switch tag {
+	case smallIntegerExt:
+		i, err := d.uint8()
+		return int64(i), nil, err
+	case integerExt:
+		b, err := d.next(4)
+		if err != nil {
+			return 0, nil, err
+		}
+		return int64(int32(binary.BigEndian.Uint32(b))), nil, nil
+	}
+
+	return d.bigInt(tag)
+}
+
+func (d *decoder) float() (float64, error) {
+	b, err := d.next(8)
+	if err != nil {
+		return 0, err
+	}
+
+	return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+}
+
+// bytes reads the content of an atom, a binary or a string
+func (d *decoder) bytes(tag byte) ([]byte, error) {
+	n, err := d.length(tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.next(n)
+}
+
+// term decodes a term into an interface{}
+func (d *decoder) term() (interface{}, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	tag := b[0]
+
+	switch tag {
+	case smallIntegerExt, integerExt, smallBigExt, largeBigExt:
+		i, large, err := d.integer(tag)
+		if err != nil {
+			return nil, err
+		}
+		if large != nil {
+			return large, nil
+		}
+		return i, nil
+
+	case newFloatExt:
+		return d.float()
+
+	case atomExt, smallAtomExt, atomUTF8Ext, smallAtomUTF8Ext:
+		b, err := d.bytes(tag)
+		if err != nil {
+			return nil, err
+		}
+
+		switch atom := string(b); atom {
+		case "nil":
+			return nil, nil
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		default:
+			return atom, nil
+		}
+
+	case binaryExt, stringExt:
+		b, err := d.bytes(tag)
+		return string(b), err
+
+	case nilExt:
+		return []interface{}{}, nil
+
+	case listExt, smallTupleExt, largeTupleExt:
+		n, err := d.count(tag)
+		if err != nil {
+			return nil, err
+		}
+
+		list := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			v, err := d.term()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+
+		if tag == listExt {
+			err = d.tail()
+		}
+		return list, err
+
+	case mapExt:
+		n, err := d.count(tag)
+		if err != nil {
+			return nil, err
+		}
+
+		m := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			k, err := d.term()
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.term()
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(k)] = v
+		}
+		return m, nil
+	}
+
+	return nil, fmt.Errorf("etf: unsupported tag %d", tag)
+}
+
+// tail reads the tail of a list, only proper lists ending with NIL_EXT are supported
+func (d *decoder) tail() error {
+	b, err := d.next(1)
+	if err != nil {
+		return err
+	}
+	if b[0] != nilExt {
+		return errors.New("etf: improper lists are not supported")
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package etf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshal(t *testing.T) {
+	// term_to_binary(#{<<"a">> => nil, <<"id">> => 81384788765712384, <<"s">> => [1, 300, 'atom']})
+	data := []byte{
+		131, 116, 0, 0, 0, 3,
+		109, 0, 0, 0, 1, 'a', 115, 3, 'n', 'i', 'l',
+		109, 0, 0, 0, 2, 'i', 'd', 110, 8, 0, 0, 32, 128, 192, 8, 35, 33, 1,
+		109, 0, 0, 0, 1, 's', 108, 0, 0, 0, 3, 97, 1, 98, 0, 0, 1, 44, 100, 0, 4, 'a', 't', 'o', 'm', 106,
+	}
+
+	t.Run("values", func(t *testing.T) {
+		var v interface{}
+		if err := Unmarshal(data, &v); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := map[string]interface{}{
+			"a":  nil,
+			"id": int64(81384788765712384),
+			"s":  []interface{}{int64(1), int64(300), "atom"},
+		}
+		if !reflect.DeepEqual(v, expected) {
+			t.Errorf("expected %#v, received %#v", expected, v)
+		}
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		a := "unchanged"
+		v := struct {
+			A  *string `json:"a"`
+			ID string  `json:"id"`
+			S  []interface{}
+		}{A: &a}
+		if err := Unmarshal(data, &v); err != nil {
+			t.Fatal(err)
+		}
+
+		if v.A != nil || v.ID != "81384788765712384" || len(v.S) != 3 {
+			t.Errorf("unexpected struct: %#v", v)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		var v interface{}
+		if err := Unmarshal(data[:20], &v); err != ErrTruncated {
+			t.Errorf("expected ErrTruncated, received %v", err)
+		}
+	})
+
+	t.Run("truncated large length", func(t *testing.T) {
+		// a list and a map claiming 4294967295 elements, which must be rejected before allocating them
+		list := []byte{131, 108, 255, 255, 255, 255, 97, 1}
+		m := []byte{131, 116, 255, 255, 255, 255, 109, 0, 0, 0, 1, 'a', 97, 1}
+
+		tests := []struct {
+			data []byte
+			v    interface{}
+		}{
+			{list, new(interface{})},
+			{list, new([]int)},
+			{m, new(interface{})},
+			{m, new(map[string]int)},
+			{m, new(struct{ A int })},
+		}
+		for _, test := range tests {
+			if err := Unmarshal(test.data, test.v); err != ErrTruncated {
+				t.Errorf("%T: expected ErrTruncated, received %v", test.v, err)
+			}
+		}
+	})
+}
+
+func TestUnmarshalSnowflakes(t *testing.T) {
+	type Inner struct {
+		ChannelID string `json:"channel_id"`
+	}
+	type event struct {
+		*Inner
+		ID       string   `json:"id"`
+		GuildID  string   `json:"guild_id,omitempty"`
+		Count    int64    `json:"count"`
+		Position int      `json:"position"`
+		Roles    []string `json:"roles"`
+		Shard    [2]int   `json:"shard"`
+		Ignored  string   `json:"-"`
+	}
+
+	data, err := Marshal(map[string]interface{}{
+		"id":         int64(7),                  // a small snowflake, sent as a small integer
+		"guild_id":   "372539957824323584",      // a snowflake sent as a binary
+		"channel_id": int64(532935925194555392), // a snowflake sent as a big integer
+		"count":      int64(1) << 40,            // a large integer which isn't an ID
+		"position":   int64(-3),
+		"roles":      []interface{}{int64(1), "2"},
+		"shard":      []interface{}{int64(0), int64(2)},
+		"unknown":    map[string]interface{}{"nested": []interface{}{1.5}},
+		"Ignored":    "value",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var e event
+	if err = Unmarshal(data, &e); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := event{
+		Inner:    &Inner{ChannelID: "532935925194555392"},
+		ID:       "7",
+		GuildID:  "372539957824323584",
+		Count:    1 << 40,
+		Position: -3,
+		Roles:    []string{"1", "2"},
+		Shard:    [2]int{0, 2},
+	}
+	if !reflect.DeepEqual(e, expected) {
+		t.Errorf("expected %#v, received %#v", expected, e)
+	}
+
+	var ids struct {
+		ID int64 `json:"id"`
+	}
+	data, _ = Marshal(map[string]interface{}{"id": "532935925194555392"})
+	if err = Unmarshal(data, &ids); err != nil || ids.ID != 532935925194555392 {
+		t.Errorf("a snowflake string was not decoded into an int64: %d, %v", ids.ID, err)
+	}
+
+	var small struct {
+		Position int8 `json:"position"`
+	}
+	data, _ = Marshal(map[string]interface{}{"position": 300})
+	if err = Unmarshal(data, &small); err == nil {
+		t.Error("expected an overflow error")
+	}
+}
+
+type raw []byte
+
+func (r *raw) UnmarshalETF(data []byte) error {
+	*r = data
+	return nil
+}
+
+func TestUnmarshaler(t *testing.T) {
+	data, err := Marshal(map[string]interface{}{"op": 0, "d": map[string]interface{}{"id": int64(1) << 40}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var payload struct {
+		OP int `json:"op"`
+		D  raw `json:"d"`
+	}
+	if err = Unmarshal(data, &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	var d struct {
+		ID string `json:"id"`
+	}
+	if err = Unmarshal(payload.D, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.ID != "1099511627776" {
+		t.Errorf("unexpected ID %s", d.ID)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	payload := struct {
+		OP int         `json:"op"`
+		D  interface{} `json:"d"`
+	}{2, map[string]interface{}{
+		"token":    "token \"quoted\"\n",
+		"shard":    []int{0, 1},
+		"presence": nil,
+		"large":    int64(1) << 40,
+		"ratio":    0.5,
+		"compress": false,
+	}}
+
+	data, err := Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err = Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"op": int64(2),
+		"d": map[string]interface{}{
+			"token":    "token \"quoted\"\n",
+			"shard":    []interface{}{int64(0), int64(1)},
+			"presence": nil,
+			"large":    int64(1) << 40,
+			"ratio":    0.5,
+			"compress": false,
+		},
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("expected %#v, received %#v", expected, decoded)
+	}
+}
@@ -0,0 +1,479 @@
+package gocord
+
+import "github.com/Soumil07/gocord/rest"
+
+// Contains the structs of every gateway dispatch event, and the typed methods registering their handlers
+
+// Ready is dispatched once a shard has identified, including the session ID and the unavailable guilds
+type Ready struct {
+	Version   int      `json:"v"`
+	User      *User    `json:"user"`
+	Guilds    []*Guild `json:"guilds"`
+	SessionID string   `json:"session_id"`
+	Shard     [2]int   `json:"shard,omitempty"`
+}
+
+// Resumed is dispatched once a resumed session has replayed all missed events
+type Resumed struct{}
+
+// GuildCreate is dispatched when a new guild is added. Lazy loaded guilds sent after Ready are not dispatched
+type GuildCreate struct {
+	*Guild
+}
+
+// GuildUpdate is dispatched when a guild is updated
+type GuildUpdate struct {
+	*Guild
+}
+
+// GuildDelete is dispatched when a guild becomes unavailable, or the user leaves or is removed from it
+type GuildDelete struct {
+	ID          string `json:"id"`
+	Unavailable bool   `json:"unavailable,omitempty"` // false when the user left or was removed from the guild
+}
+
+// GuildBanAdd is dispatched when a user is banned from a guild
+type GuildBanAdd struct {
+	GuildID string `json:"guild_id"`
+	User    *User  `json:"user"`
+}
+
+// GuildBanRemove is dispatched when a user is unbanned from a guild
+type GuildBanRemove struct {
+	GuildID string `json:"guild_id"`
+	User    *User  `json:"user"`
+}
+
+// GuildEmojisUpdate is dispatched when the emojis of a guild are updated
+type GuildEmojisUpdate struct {
+	GuildID string  `json:"guild_id"`
+	Emojis  []Emoji `json:"emojis"`
+}
+
+// GuildIntegrationsUpdate is dispatched when the integrations of a guild are updated
+type GuildIntegrationsUpdate struct {
+	GuildID string `json:"guild_id"`
+}
+
+// GuildMemberAdd is dispatched when a user joins a guild
+type GuildMemberAdd struct {
+	*Member
+	GuildID string `json:"guild_id"`
+}
+
+// GuildMemberUpdate is dispatched when a guild member is updated
+type GuildMemberUpdate struct {
+	GuildID string   `json:"guild_id"`
+	Roles   []string `json:"roles"`
+	User    *User    `json:"user"`
+	Nick    string   `json:"nick,omitempty"`
+}
+
+// GuildMemberRemove is dispatched when a user leaves or is removed from a guild
+type GuildMemberRemove struct {
+	GuildID string `json:"guild_id"`
+	User    *User  `json:"user"`
+}
+
+// GuildMembersChunk is dispatched in response to a request guild members payload
+type GuildMembersChunk struct {
+	GuildID    string                `json:"guild_id"`
+	Members    []Member              `json:"members"`
+	ChunkIndex int                   `json:"chunk_index"`
+	ChunkCount int                   `json:"chunk_count"`
+	NotFound   []string              `json:"not_found,omitempty"`
+	Presences  []GuildMemberPresence `json:"presences,omitempty"`
+	Nonce      string                `json:"nonce,omitempty"`
+}
+
+// GuildRoleCreate is dispatched when a role is created
+type GuildRoleCreate struct {
+	GuildID string `json:"guild_id"`
+	Role    *Role  `json:"role"`
+}
+
+// GuildRoleUpdate is dispatched when a role is updated
+type GuildRoleUpdate struct {
+	GuildID string `json:"guild_id"`
+	Role    *Role  `json:"role"`
+}
+
+// GuildRoleDelete is dispatched when a role is deleted
+type GuildRoleDelete struct {
+	GuildID string `json:"guild_id"`
+	RoleID  string `json:"role_id"`
+}
+
+// ChannelCreate is dispatched when a channel is created
+type ChannelCreate struct {
+	*Channel
+}
+
+// ChannelUpdate is dispatched when a channel is updated
+type ChannelUpdate struct {
+	*Channel
+}
+
+// ChannelDelete is dispatched when a channel is deleted
+type ChannelDelete struct {
+	*Channel
+}
+
+// ChannelPinsUpdate is dispatched when a message is pinned or unpinned
+type ChannelPinsUpdate struct {
+	GuildID          string `json:"guild_id,omitempty"`
+	ChannelID        string `json:"channel_id"`
+	LastPinTimestamp string `json:"last_pin_timestamp,omitempty"`
+}
+
+// InviteCreate is dispatched when an invite is created
+type InviteCreate struct {
+	ChannelID string `json:"channel_id"`
+	Code      string `json:"code"`
+	CreatedAt string `json:"created_at"`
+	GuildID   string `json:"guild_id,omitempty"`
+	Inviter   *User  `json:"inviter,omitempty"`
+	MaxAge    int    `json:"max_age"`
+	MaxUses   int    `json:"max_uses"`
+	Temporary bool   `json:"temporary"`
+	Uses      int    `json:"uses"`
+}
+
+// InviteDelete is dispatched when an invite is deleted
+type InviteDelete struct {
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id,omitempty"`
+	Code      string `json:"code"`
+}
+
+// MessageCreate is dispatched when a message is sent
+type MessageCreate struct {
+	*Message
+}
+
+// MessageUpdate is dispatched when a message is edited. NOTE: the message may be partial, only the ID
+// and channel ID are guaranteed
+type MessageUpdate struct {
+	*Message
+}
+
+// MessageDelete is dispatched when a message is deleted
+type MessageDelete struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id,omitempty"`
+}
+
+// MessageDeleteBulk is dispatched when messages are bulk deleted
+type MessageDeleteBulk struct {
+	IDs       []string `json:"ids"`
+	ChannelID string   `json:"channel_id"`
+	GuildID   string   `json:"guild_id,omitempty"`
+}
+
+// MessageReactionAdd is dispatched when a user reacts to a message
+type MessageReactionAdd struct {
+	UserID    string  `json:"user_id"`
+	ChannelID string  `json:"channel_id"`
+	MessageID string  `json:"message_id"`
+	GuildID   string  `json:"guild_id,omitempty"`
+	Member    *Member `json:"member,omitempty"`
+	Emoji     Emoji   `json:"emoji"`
+}
+
+// MessageReactionRemove is dispatched when a user removes a reaction from a message
+type MessageReactionRemove struct {
+	UserID    string `json:"user_id"`
+	ChannelID string `json:"channel_id"`
+	MessageID string `json:"message_id"`
+	GuildID   string `json:"guild_id,omitempty"`
+	Emoji     Emoji  `json:"emoji"`
+}
+
+// MessageReactionRemoveAll is dispatched when all reactions are removed from a message
+type MessageReactionRemoveAll struct {
+	ChannelID string `json:"channel_id"`
+	MessageID string `json:"message_id"`
+	GuildID   string `json:"guild_id,omitempty"`
+}
+
+// MessageReactionRemoveEmoji is dispatched when all reactions of an emoji are removed from a message
+type MessageReactionRemoveEmoji struct {
+	ChannelID string `json:"channel_id"`
+	MessageID string `json:"message_id"`
+	GuildID   string `json:"guild_id,omitempty"`
+	Emoji     Emoji  `json:"emoji"`
+}
+
+// PresenceUpdate is dispatched when the presence of a guild member is updated
+type PresenceUpdate struct {
+	*GuildMemberPresence
+}
+
+// TypingStart is dispatched when a user starts typing in a channel
+type TypingStart struct {
+	ChannelID string  `json:"channel_id"`
+	GuildID   string  `json:"guild_id,omitempty"`
+	UserID    string  `json:"user_id"`
+	Timestamp int64   `json:"timestamp"` // unix time in seconds
+	Member    *Member `json:"member,omitempty"`
+}
+
+// UserUpdate is dispatched when the current user is updated
+type UserUpdate struct {
+	*User
+}
+
+// VoiceStateUpdate is dispatched when a user joins, leaves or moves between voice channels
+type VoiceStateUpdate struct {
+	*VoiceState
+}
+
+// VoiceServerUpdate is dispatched when the voice server of a guild is assigned or changed
+type VoiceServerUpdate struct {
+	Token    string `json:"token"`
+	GuildID  string `json:"guild_id"`
+	Endpoint string `json:"endpoint"`
+}
+
+// WebhooksUpdate is dispatched when the webhooks of a channel are updated
+type WebhooksUpdate struct {
+	GuildID   string `json:"guild_id"`
+	ChannelID string `json:"channel_id"`
+}
+
+// eventFactories return empty structs to decode each dispatch event into
+var eventFactories = map[string]func() interface{}{
+	ReadyEvent:                      func() interface{} { return &Ready{} },
+	ResumedEvent:                    func() interface{} { return &Resumed{} },
+	GuildCreateEvent:                func() interface{} { return &GuildCreate{} },
+	GuildUpdateEvent:                func() interface{} { return &GuildUpdate{} },
+	GuildDeleteEvent:                func() interface{} { return &GuildDelete{} },
+	GuildBanAddEvent:                func() interface{} { return &GuildBanAdd{} },
+	GuildBanRemoveEvent:             func() interface{} { return &GuildBanRemove{} },
+	GuildEmojisUpdateEvent:          func() interface{} { return &GuildEmojisUpdate{} },
+	GuildIntegrationsUpdateEvent:    func() interface{} { return &GuildIntegrationsUpdate{} },
+	GuildMemberAddEvent:             func() interface{} { return &GuildMemberAdd{} },
+	GuildMemberUpdateEvent:          func() interface{} { return &GuildMemberUpdate{} },
+	GuildMemberRemoveEvent:          func() interface{} { return &GuildMemberRemove{} },
+	GuildMembersChunkEvent:          func() interface{} { return &GuildMembersChunk{} },
+	GuildRoleCreateEvent:            func() interface{} { return &GuildRoleCreate{} },
+	GuildRoleUpdateEvent:            func() interface{} { return &GuildRoleUpdate{} },
+	GuildRoleDeleteEvent:            func() interface{} { return &GuildRoleDelete{} },
+	ChannelCreateEvent:              func() interface{} { return &ChannelCreate{} },
+	ChannelUpdateEvent:              func() interface{} { return &ChannelUpdate{} },
+	ChannelDeleteEvent:              func() interface{} { return &ChannelDelete{} },
+	ChannelPinsUpdateEvent:          func() interface{} { return &ChannelPinsUpdate{} },
+	InviteCreateEvent:               func() interface{} { return &InviteCreate{} },
+	InviteDeleteEvent:               func() interface{} { return &InviteDelete{} },
+	MessageEvent:                    func() interface{} { return &MessageCreate{} },
+	MessageUpdateEvent:              func() interface{} { return &MessageUpdate{} },
+	MessageDeleteEvent:              func() interface{} { return &MessageDelete{} },
+	MessageDeleteBulkEvent:          func() interface{} { return &MessageDeleteBulk{} },
+	MessageReactionAddEvent:         func() interface{} { return &MessageReactionAdd{} },
+	MessageReactionRemoveEvent:      func() interface{} { return &MessageReactionRemove{} },
+	MessageReactionRemoveAllEvent:   func() interface{} { return &MessageReactionRemoveAll{} },
+	MessageReactionRemoveEmojiEvent: func() interface{} { return &MessageReactionRemoveEmoji{} },
+	PresenceUpdateEvent:             func() interface{} { return &PresenceUpdate{} },
+	TypingStartEvent:                func() interface{} { return &TypingStart{} },
+	UserUpdateEvent:                 func() interface{} { return &UserUpdate{} },
+	VoiceStateUpdateEvent:           func() interface{} { return &VoiceStateUpdate{} },
+	VoiceServerUpdateEvent:          func() interface{} { return &VoiceServerUpdate{} },
+	WebhooksUpdateEvent:             func() interface{} { return &WebhooksUpdate{} },
+}
+
+// shardDisconnectEvent is the name ShardDisconnect events are dispatched with
+const shardDisconnectEvent = "shardDisconnect"
+
+// OnShardDisconnect registers a handler called whenever a shard loses its connection
+func (c *Cluster) OnShardDisconnect(handler func(*Shard, *ShardDisconnect)) {
+	c.Subscribe(shardDisconnectEvent, handler)
+}
+
+// rateLimitEvent is the name rest.RateLimit events are dispatched with
+const rateLimitEvent = "rateLimit"
+
+// OnRateLimit registers a handler called whenever a REST request hits a rate limit
+func (c *Cluster) OnRateLimit(handler func(*rest.RateLimit)) {
+	c.Subscribe(rateLimitEvent, handler)
+}
+
+// OnReady registers a handler for READY events
+func (c *Cluster) OnReady(handler func(*Shard, *Ready)) {
+	c.Subscribe(ReadyEvent, handler)
+}
+
+// OnResumed registers a handler for RESUMED events
+func (c *Cluster) OnResumed(handler func(*Shard, *Resumed)) {
+	c.Subscribe(ResumedEvent, handler)
+}
+
+// OnGuildCreate registers a handler for GUILD_CREATE events
+func (c *Cluster) OnGuildCreate(handler func(*Shard, *GuildCreate)) {
+	c.Subscribe(GuildCreateEvent, handler)
+}
+
+// OnGuildUpdate registers a handler for GUILD_UPDATE events
+func (c *Cluster) OnGuildUpdate(handler func(*Shard, *GuildUpdate)) {
+	c.Subscribe(GuildUpdateEvent, handler)
+}
+
+// OnGuildDelete registers a handler for GUILD_DELETE events
+func (c *Cluster) OnGuildDelete(handler func(*Shard, *GuildDelete)) {
+	c.Subscribe(GuildDeleteEvent, handler)
+}
+
+// OnGuildBanAdd registers a handler for GUILD_BAN_ADD events
+func (c *Cluster) OnGuildBanAdd(handler func(*Shard, *GuildBanAdd)) {
+	c.Subscribe(GuildBanAddEvent, handler)
+}
+
+// OnGuildBanRemove registers a handler for GUILD_BAN_REMOVE events
+func (c *Cluster) OnGuildBanRemove(handler func(*Shard, *GuildBanRemove)) {
+	c.Subscribe(GuildBanRemoveEvent, handler)
+}
+
+// OnGuildEmojisUpdate registers a handler for GUILD_EMOJIS_UPDATE events
+func (c *Cluster) OnGuildEmojisUpdate(handler func(*Shard, *GuildEmojisUpdate)) {
+	c.Subscribe(GuildEmojisUpdateEvent, handler)
+}
+
+// OnGuildIntegrationsUpdate registers a handler for GUILD_INTEGRATIONS_UPDATE events
+func (c *Cluster) OnGuildIntegrationsUpdate(handler func(*Shard, *GuildIntegrationsUpdate)) {
+	c.Subscribe(GuildIntegrationsUpdateEvent, handler)
+}
+
+// OnGuildMemberAdd registers a handler for GUILD_MEMBER_ADD events
+func (c *Cluster) OnGuildMemberAdd(handler func(*Shard, *GuildMemberAdd)) {
+	c.Subscribe(GuildMemberAddEvent, handler)
+}
+
+// OnGuildMemberUpdate registers a handler for GUILD_MEMBER_UPDATE events
+func (c *Cluster) OnGuildMemberUpdate(handler func(*Shard, *GuildMemberUpdate)) {
+	c.Subscribe(GuildMemberUpdateEvent, handler)
+}
+
+// OnGuildMemberRemove registers a handler for GUILD_MEMBER_REMOVE events
+func (c *Cluster) OnGuildMemberRemove(handler func(*Shard, *GuildMemberRemove)) {
+	c.Subscribe(GuildMemberRemoveEvent, handler)
+}
+
+// OnGuildMembersChunk registers a handler for GUILD_MEMBERS_CHUNK events
+func (c *Cluster) OnGuildMembersChunk(handler func(*Shard, *GuildMembersChunk)) {
+	c.Subscribe(GuildMembersChunkEvent, handler)
+}
+
+// OnGuildRoleCreate registers a handler for GUILD_ROLE_CREATE events
+func (c *Cluster) OnGuildRoleCreate(handler func(*Shard, *GuildRoleCreate)) {
+	c.Subscribe(GuildRoleCreateEvent, handler)
+}
+
+// OnGuildRoleUpdate registers a handler for GUILD_ROLE_UPDATE events
+func (c *Cluster) OnGuildRoleUpdate(handler func(*Shard, *GuildRoleUpdate)) {
+	c.Subscribe(GuildRoleUpdateEvent, handler)
+}
+
+// OnGuildRoleDelete registers a handler for GUILD_ROLE_DELETE events
+func (c *Cluster) OnGuildRoleDelete(handler func(*Shard, *GuildRoleDelete)) {
+	c.Subscribe(GuildRoleDeleteEvent, handler)
+}
+
+// OnChannelCreate registers a handler for CHANNEL_CREATE events
+func (c *Cluster) OnChannelCreate(handler func(*Shard, *ChannelCreate)) {
+	c.Subscribe(ChannelCreateEvent, handler)
+}
+
+// OnChannelUpdate registers a handler for CHANNEL_UPDATE events
+func (c *Cluster) OnChannelUpdate(handler func(*Shard, *ChannelUpdate)) {
+	c.Subscribe(ChannelUpdateEvent, handler)
+}
+
+// OnChannelDelete registers a handler for CHANNEL_DELETE events
+func (c *Cluster) OnChannelDelete(handler func(*Shard, *ChannelDelete)) {
+	c.Subscribe(ChannelDeleteEvent, handler)
+}
+
+// OnChannelPinsUpdate registers a handler for CHANNEL_PINS_UPDATE events
+func (c *Cluster) OnChannelPinsUpdate(handler func(*Shard, *ChannelPinsUpdate)) {
+	c.Subscribe(ChannelPinsUpdateEvent, handler)
+}
+
+// OnInviteCreate registers a handler for INVITE_CREATE events
+func (c *Cluster) OnInviteCreate(handler func(*Shard, *InviteCreate)) {
+	c.Subscribe(InviteCreateEvent, handler)
+}
+
+// OnInviteDelete registers a handler for INVITE_DELETE events
+func (c *Cluster) OnInviteDelete(handler func(*Shard, *InviteDelete)) {
+	c.Subscribe(InviteDeleteEvent, handler)
+}
+
+// OnMessageCreate registers a handler for MESSAGE_CREATE events
+func (c *Cluster) OnMessageCreate(handler func(*Shard, *MessageCreate)) {
+	c.Subscribe(MessageEvent, handler)
+}
+
+// OnMessageUpdate registers a handler for MESSAGE_UPDATE events
+func (c *Cluster) OnMessageUpdate(handler func(*Shard, *MessageUpdate)) {
+	c.Subscribe(MessageUpdateEvent, handler)
+}
+
+// OnMessageDelete registers a handler for MESSAGE_DELETE events
+func (c *Cluster) OnMessageDelete(handler func(*Shard, *MessageDelete)) {
+	c.Subscribe(MessageDeleteEvent, handler)
+}
+
+// OnMessageDeleteBulk registers a handler for MESSAGE_DELETE_BULK events
+func (c *Cluster) OnMessageDeleteBulk(handler func(*Shard, *MessageDeleteBulk)) {
+	c.Subscribe(MessageDeleteBulkEvent, handler)
+}
+
+// OnMessageReactionAdd registers a handler for MESSAGE_REACTION_ADD events
+func (c *Cluster) OnMessageReactionAdd(handler func(*Shard, *MessageReactionAdd)) {
+	c.Subscribe(MessageReactionAddEvent, handler)
+}
+
+// OnMessageReactionRemove registers a handler for MESSAGE_REACTION_REMOVE events
+func (c *Cluster) OnMessageReactionRemove(handler func(*Shard, *MessageReactionRemove)) {
+	c.Subscribe(MessageReactionRemoveEvent, handler)
+}
+
+// OnMessageReactionRemoveAll registers a handler for MESSAGE_REACTION_REMOVE_ALL events
+func (c *Cluster) OnMessageReactionRemoveAll(handler func(*Shard, *MessageReactionRemoveAll)) {
+	c.Subscribe(MessageReactionRemoveAllEvent, handler)
+}
+
+// OnMessageReactionRemoveEmoji registers a handler for MESSAGE_REACTION_REMOVE_EMOJI events
+func (c *Cluster) OnMessageReactionRemoveEmoji(handler func(*Shard, *MessageReactionRemoveEmoji)) {
+	c.Subscribe(MessageReactionRemoveEmojiEvent, handler)
+}
+
+// OnPresenceUpdate registers a handler for PRESENCE_UPDATE events
+func (c *Cluster) OnPresenceUpdate(handler func(*Shard, *PresenceUpdate)) {
+	c.Subscribe(PresenceUpdateEvent, handler)
+}
+
+// OnTypingStart registers a handler for TYPING_START events
+func (c *Cluster) OnTypingStart(handler func(*Shard, *TypingStart)) {
+	c.Subscribe(TypingStartEvent, handler)
+}
+
+// OnUserUpdate registers a handler for USER_UPDATE events
+func (c *Cluster) OnUserUpdate(handler func(*Shard, *UserUpdate)) {
+	c.Subscribe(UserUpdateEvent, handler)
+}
+
+// OnVoiceStateUpdate registers a handler for VOICE_STATE_UPDATE events
+func (c *Cluster) OnVoiceStateUpdate(handler func(*Shard, *VoiceStateUpdate)) {
+	c.Subscribe(VoiceStateUpdateEvent, handler)
+}
+
+// OnVoiceServerUpdate registers a handler for VOICE_SERVER_UPDATE events
+func (c *Cluster) OnVoiceServerUpdate(handler func(*Shard, *VoiceServerUpdate)) {
+	c.Subscribe(VoiceServerUpdateEvent, handler)
+}
+
+// OnWebhooksUpdate registers a handler for WEBHOOKS_UPDATE events
+func (c *Cluster) OnWebhooksUpdate(handler func(*Shard, *WebhooksUpdate)) {
+	c.Subscribe(WebhooksUpdateEvent, handler)
+}
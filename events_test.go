@@ -0,0 +1,56 @@
+package gocord
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventFactories(t *testing.T) {
+	for event := range eventIntents {
+		if _, ok := eventFactories[event]; !ok {
+			t.Errorf("no struct for the event %s", event)
+		}
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	c := newTestCluster("")
+	s := NewShard(0, c)
+
+	deleted := make(chan *MessageDelete, 1)
+	c.OnMessageDelete(func(s *Shard, m *MessageDelete) {
+		deleted <- m
+	})
+
+	err := s.onDispatch(MessageDeleteEvent, []byte(`{"id":"1","channel_id":"2","guild_id":"3"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case m := <-deleted:
+		if m.ID != "1" || m.ChannelID != "2" || m.GuildID != "3" {
+			t.Errorf("unexpected event: %#v", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the handler was not called")
+	}
+
+	t.Run("guild cache", func(t *testing.T) {
+		s.onDispatch(GuildCreateEvent, []byte(`{"id":"1","name":"gocord","member_count":10}`))
+		s.onDispatch(GuildUpdateEvent, []byte(`{"id":"1","name":"gocord 2"}`))
+
+		cached, ok := s.GuildCache.Get("1")
+		if !ok {
+			t.Fatal("the guild was not cached")
+		}
+		if guild := cached.(*Guild); guild.Name != "gocord 2" || guild.MemberCount != 10 {
+			t.Errorf("unexpected cached guild: %#v", guild)
+		}
+
+		s.onDispatch(GuildDeleteEvent, []byte(`{"id":"1"}`))
+		if s.GuildCache.Has("1") {
+			t.Error("the guild was not removed from the cache")
+		}
+	})
+}
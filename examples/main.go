@@ -23,10 +23,10 @@ func main() {
 		Debug:  true,
 	})
 
-	c.Subscribe("ready", func(s *gocord.Shard) {
+	c.OnReady(func(s *gocord.Shard, r *gocord.Ready) {
 		fmt.Println("Ready to roll!")
 	})
-	c.Subscribe("message", func(s *gocord.Shard, m *gocord.Message) {
+	c.OnMessageCreate(func(s *gocord.Shard, m *gocord.MessageCreate) {
 		if m.Content == "gocord ping" {
 			c.CreateMessage(m.ChannelID, "Pong!")
 		} else if m.Content == "gocord file" {
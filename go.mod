@@ -1,6 +1,11 @@
 module github.com/Soumil07/gocord
 
+go 1.26.0
+
 require (
 	github.com/euskadi31/go-eventemitter v1.1.0
 	github.com/gorilla/websocket v1.4.0
+	golang.org/x/crypto v0.57.0
 )
+
+require golang.org/x/sys v0.48.0 // indirect
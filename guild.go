@@ -1,8 +1,10 @@
 package gocord
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/Soumil07/gocord/rest"
 )
@@ -40,7 +42,7 @@ type Guild struct {
 	Large       bool                  `json:"large,omitempty"`
 	Unavailable bool                  `json:"unavailable,omitempty"`
 	MemberCount int                   `json:"member_count,omitempty"`
-	Members     []Member              `json:"members,omitempty"`
+	Members     []Member              `json:"members,omitempty"` // read with Shard.CachedMembers for cached guilds
 	Channels    []Channel             `json:"channels,omitempty"`
 	Presences   []GuildMemberPresence `json:"presences,omitempty"`
 }
@@ -49,35 +51,367 @@ func (g *Guild) String() string {
 	return g.Name
 }
 
+// Role represents a role of a guild
 type Role struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Color       int    `json:"color"`
+	Hoist       bool   `json:"hoist"` // whether members are displayed separately in the member list
+	Position    int    `json:"position"`
+	Permissions int    `json:"permissions"` // bitfield permissions
+	Managed     bool   `json:"managed"`     // whether the role is managed by an integration
+	Mentionable bool   `json:"mentionable"`
 }
 
+// Emoji represents a custom emoji of a guild, or a unicode emoji when the ID is empty
 type Emoji struct {
+	ID            string   `json:"id,omitempty"`
+	Name          string   `json:"name"`
+	Roles         []string `json:"roles,omitempty"` // the IDs of the roles allowed to use the emoji
+	User          *User    `json:"user,omitempty"`  // the user who created the emoji
+	RequireColons bool     `json:"require_colons,omitempty"`
+	Managed       bool     `json:"managed,omitempty"`
+	Animated      bool     `json:"animated,omitempty"`
+	Available     bool     `json:"available,omitempty"`
 }
 
+// Member represents a user in a guild
 type Member struct {
+	User         *User    `json:"user,omitempty"`
+	Nick         string   `json:"nick,omitempty"`
+	Roles        []string `json:"roles"`
+	JoinedAt     string   `json:"joined_at"`
+	PremiumSince string   `json:"premium_since,omitempty"` // when the member started boosting the guild
+	Deaf         bool     `json:"deaf"`
+	Mute         bool     `json:"mute"`
+	// CommunicationDisabledUntil is when the timeout of the member ends, empty if they aren't timed out
+	CommunicationDisabledUntil string `json:"communication_disabled_until,omitempty"`
 }
 
+// GuildMemberPresence represents the status and activities of a member
 type GuildMemberPresence struct {
+	User         *User        `json:"user"` // only the ID is guaranteed
+	GuildID      string       `json:"guild_id,omitempty"`
+	Status       string       `json:"status"` // online, idle, dnd or offline
+	Activities   []Activity   `json:"activities"`
+	ClientStatus ClientStatus `json:"client_status"`
 }
 
-func (c *Cluster) BanMember(guildID, userID, reason string, deleteMessageDays int) (err error) {
+// Activity represents a game, stream or other activity of a user
+type Activity struct {
+	Name          string `json:"name"`
+	Type          int    `json:"type"`
+	URL           string `json:"url,omitempty"`
+	CreatedAt     int64  `json:"created_at,omitempty"` // unix timestamp in milliseconds
+	ApplicationID string `json:"application_id,omitempty"`
+	Details       string `json:"details,omitempty"`
+	State         string `json:"state,omitempty"`
+	Emoji         *Emoji `json:"emoji,omitempty"` // the emoji of a custom status
+}
+
+// ClientStatus is the status of a user on every platform, empty when they aren't active on it
+type ClientStatus struct {
+	Desktop string `json:"desktop,omitempty"`
+	Mobile  string `json:"mobile,omitempty"`
+	Web     string `json:"web,omitempty"`
+}
+
+// CreateRole are the settings of a new role
+type CreateRole struct {
+	Name        string `json:"name,omitempty"`
+	Permissions *int   `json:"permissions,omitempty"` // the permissions of @everyone when nil
+	Color       int    `json:"color,omitempty"`
+	Hoist       bool   `json:"hoist,omitempty"`
+	Mentionable bool   `json:"mentionable,omitempty"`
+}
+
+// ModifyRole are the settings of a role to modify, nil fields are left unchanged
+type ModifyRole struct {
+	Name        string `json:"name,omitempty"`
+	Permissions *int   `json:"permissions,omitempty"`
+	Color       *int   `json:"color,omitempty"`
+	Hoist       *bool  `json:"hoist,omitempty"`
+	Mentionable *bool  `json:"mentionable,omitempty"`
+}
+
+// RolePosition is the new position of a role, used when reordering the roles of a guild
+type RolePosition struct {
+	ID       string `json:"id"`
+	Position int    `json:"position"`
+}
+
+// ModifyMember are the settings of a member to modify, nil fields are left unchanged
+type ModifyMember struct {
+	Nick      *string
+	Roles     []string // replaces every role of the member
+	Mute      *bool
+	Deaf      *bool
+	ChannelID *string // moves the member to a voice channel, an empty string disconnects them
+	// TimeoutUntil times the member out until the time, a zero time ends their timeout
+	TimeoutUntil *time.Time
+}
+
+// MarshalJSON encodes the settings, sending null to disconnect the member or end their timeout
+func (m ModifyMember) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]interface{})
+	if m.Nick != nil {
+		fields["nick"] = *m.Nick
+	}
+	if m.Roles != nil {
+		fields["roles"] = m.Roles
+	}
+	if m.Mute != nil {
+		fields["mute"] = *m.Mute
+	}
+	if m.Deaf != nil {
+		fields["deaf"] = *m.Deaf
+	}
+	if m.ChannelID != nil {
+		fields["channel_id"] = nullable(*m.ChannelID)
+	}
+	if m.TimeoutUntil != nil {
+		fields["communication_disabled_until"] = nil
+		if !m.TimeoutUntil.IsZero() {
+			fields["communication_disabled_until"] = m.TimeoutUntil.UTC().Format(time.RFC3339)
+		}
+	}
+
+	return json.Marshal(fields)
+}
+
+// nullable returns nil for an empty string, encoded as null
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+
+	return s
+}
+
+// VoiceState represents the voice connection status of a user
+type VoiceState struct {
+	GuildID    string  `json:"guild_id,omitempty"`
+	ChannelID  string  `json:"channel_id"` // empty when the user left the voice channel
+	UserID     string  `json:"user_id"`
+	Member     *Member `json:"member,omitempty"`
+	SessionID  string  `json:"session_id"`
+	Deaf       bool    `json:"deaf"`
+	Mute       bool    `json:"mute"`
+	SelfDeaf   bool    `json:"self_deaf"`
+	SelfMute   bool    `json:"self_mute"`
+	SelfStream bool    `json:"self_stream,omitempty"`
+	Suppress   bool    `json:"suppress"`
+}
+
+// BanMember bans a member, deleting their messages of the last days. The reason is recorded in the audit log
+func (c *Cluster) BanMember(guildID, userID, reason string, deleteMessageDays int, options ...rest.RequestOption) error {
+	return c.BanMemberContext(context.Background(), guildID, userID, reason, deleteMessageDays, options...)
+}
+
+// BanMemberContext is BanMember with a context
+func (c *Cluster) BanMemberContext(ctx context.Context, guildID, userID, reason string, deleteMessageDays int, options ...rest.RequestOption) (err error) {
 	endpoint := rest.GuildBanMember(guildID, userID)
 
 	body, err := json.Marshal(&struct {
-		DeleteMessageDays int    `json:"delete-message-days"`
-		Reason            string `json:"reason"`
-	}{deleteMessageDays, reason})
+		DeleteMessageDays int `json:"delete_message_days"`
+	}{deleteMessageDays})
 	if err != nil {
 		return
 	}
 
-	err = c.Rest.Do(http.MethodPut, endpoint, body, nil)
+	options = append([]rest.RequestOption{rest.WithReason(reason)}, options...)
+	err = c.Rest.DoWithOptions(ctx, http.MethodPut, endpoint, body, nil, options...)
 	return
 }
 
-func (c *Cluster) UnbanMember(guildID, userID string) (err error) {
+// UnbanMember revokes the ban of a user. Pass rest.WithReason to record a reason in the audit log
+func (c *Cluster) UnbanMember(guildID, userID string, options ...rest.RequestOption) error {
+	return c.UnbanMemberContext(context.Background(), guildID, userID, options...)
+}
+
+// UnbanMemberContext is UnbanMember with a context
+func (c *Cluster) UnbanMemberContext(ctx context.Context, guildID, userID string, options ...rest.RequestOption) (err error) {
 	endpoint := rest.GuildBanMember(guildID, userID)
-	err = c.Rest.Do(http.MethodDelete, endpoint, nil, nil)
+	err = c.Rest.DoWithOptions(ctx, http.MethodDelete, endpoint, nil, nil, options...)
+	return
+}
+
+// FetchRoles fetches the roles of a guild
+func (c *Cluster) FetchRoles(guildID string) ([]*Role, error) {
+	return c.FetchRolesContext(context.Background(), guildID)
+}
+
+// FetchRolesContext is FetchRoles with a context
+func (c *Cluster) FetchRolesContext(ctx context.Context, guildID string) (roles []*Role, err error) {
+	endpoint := rest.GuildRoles(guildID)
+	err = c.Rest.DoContext(ctx, http.MethodGet, endpoint, nil, &roles)
+	return
+}
+
+// CreateRole creates a role in a guild. Pass rest.WithReason to record a reason in the audit log
+func (c *Cluster) CreateRole(guildID string, data CreateRole, options ...rest.RequestOption) (*Role, error) {
+	return c.CreateRoleContext(context.Background(), guildID, data, options...)
+}
+
+// CreateRoleContext is CreateRole with a context
+func (c *Cluster) CreateRoleContext(ctx context.Context, guildID string, data CreateRole, options ...rest.RequestOption) (r *Role, err error) {
+	endpoint := rest.GuildRoles(guildID)
+
+	body, err := json.Marshal(&data)
+	if err != nil {
+		return
+	}
+
+	err = c.Rest.DoWithOptions(ctx, http.MethodPost, endpoint, body, &r, options...)
+	return
+}
+
+// ModifyRole updates the settings of a role. Pass rest.WithReason to record a reason in the audit log
+func (c *Cluster) ModifyRole(guildID, roleID string, data ModifyRole, options ...rest.RequestOption) (*Role, error) {
+	return c.ModifyRoleContext(context.Background(), guildID, roleID, data, options...)
+}
+
+// ModifyRoleContext is ModifyRole with a context
+func (c *Cluster) ModifyRoleContext(ctx context.Context, guildID, roleID string, data ModifyRole, options ...rest.RequestOption) (r *Role, err error) {
+	endpoint := rest.GuildRole(guildID, roleID)
+
+	body, err := json.Marshal(&data)
+	if err != nil {
+		return
+	}
+
+	err = c.Rest.DoWithOptions(ctx, http.MethodPatch, endpoint, body, &r, options...)
+	return
+}
+
+// ModifyRolePositions reorders the roles of a guild, returning every role of the guild
+func (c *Cluster) ModifyRolePositions(guildID string, positions []RolePosition, options ...rest.RequestOption) ([]*Role, error) {
+	return c.ModifyRolePositionsContext(context.Background(), guildID, positions, options...)
+}
+
+// ModifyRolePositionsContext is ModifyRolePositions with a context
+func (c *Cluster) ModifyRolePositionsContext(ctx context.Context, guildID string, positions []RolePosition, options ...rest.RequestOption) (roles []*Role, err error) {
+	endpoint := rest.GuildRoles(guildID)
+
+	body, err := json.Marshal(positions)
+	if err != nil {
+		return
+	}
+
+	err = c.Rest.DoWithOptions(ctx, http.MethodPatch, endpoint, body, &roles, options...)
+	return
+}
+
+// DeleteRole deletes a role. Pass rest.WithReason to record a reason in the audit log
+func (c *Cluster) DeleteRole(guildID, roleID string, options ...rest.RequestOption) error {
+	return c.DeleteRoleContext(context.Background(), guildID, roleID, options...)
+}
+
+// DeleteRoleContext is DeleteRole with a context
+func (c *Cluster) DeleteRoleContext(ctx context.Context, guildID, roleID string, options ...rest.RequestOption) (err error) {
+	endpoint := rest.GuildRole(guildID, roleID)
+	err = c.Rest.DoWithOptions(ctx, http.MethodDelete, endpoint, nil, nil, options...)
+	return
+}
+
+// FetchMember fetches a member of a guild
+func (c *Cluster) FetchMember(guildID, userID string) (*Member, error) {
+	return c.FetchMemberContext(context.Background(), guildID, userID)
+}
+
+// FetchMemberContext is FetchMember with a context
+func (c *Cluster) FetchMemberContext(ctx context.Context, guildID, userID string) (m *Member, err error) {
+	endpoint := rest.GuildMember(guildID, userID)
+	err = c.Rest.DoContext(ctx, http.MethodGet, endpoint, nil, &m)
+	return
+}
+
+// ModifyMember updates a member, such as their nickname, roles or timeout. Pass rest.WithReason to record
+// a reason in the audit log
+func (c *Cluster) ModifyMember(guildID, userID string, data ModifyMember, options ...rest.RequestOption) (*Member, error) {
+	return c.ModifyMemberContext(context.Background(), guildID, userID, data, options...)
+}
+
+// ModifyMemberContext is ModifyMember with a context
+func (c *Cluster) ModifyMemberContext(ctx context.Context, guildID, userID string, data ModifyMember, options ...rest.RequestOption) (m *Member, err error) {
+	endpoint := rest.GuildMember(guildID, userID)
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	err = c.Rest.DoWithOptions(ctx, http.MethodPatch, endpoint, body, &m, options...)
+	return
+}
+
+// KickMember removes a member from a guild. Pass rest.WithReason to record a reason in the audit log
+func (c *Cluster) KickMember(guildID, userID string, options ...rest.RequestOption) error {
+	return c.KickMemberContext(context.Background(), guildID, userID, options...)
+}
+
+// KickMemberContext is KickMember with a context
+func (c *Cluster) KickMemberContext(ctx context.Context, guildID, userID string, options ...rest.RequestOption) (err error) {
+	endpoint := rest.GuildMember(guildID, userID)
+	err = c.Rest.DoWithOptions(ctx, http.MethodDelete, endpoint, nil, nil, options...)
+	return
+}
+
+// AddMemberRole gives a role to a member. Pass rest.WithReason to record a reason in the audit log
+func (c *Cluster) AddMemberRole(guildID, userID, roleID string, options ...rest.RequestOption) error {
+	return c.AddMemberRoleContext(context.Background(), guildID, userID, roleID, options...)
+}
+
+// AddMemberRoleContext is AddMemberRole with a context
+func (c *Cluster) AddMemberRoleContext(ctx context.Context, guildID, userID, roleID string, options ...rest.RequestOption) (err error) {
+	endpoint := rest.GuildMemberRole(guildID, userID, roleID)
+	err = c.Rest.DoWithOptions(ctx, http.MethodPut, endpoint, nil, nil, options...)
+	return
+}
+
+// RemoveMemberRole takes a role from a member. Pass rest.WithReason to record a reason in the audit log
+func (c *Cluster) RemoveMemberRole(guildID, userID, roleID string, options ...rest.RequestOption) error {
+	return c.RemoveMemberRoleContext(context.Background(), guildID, userID, roleID, options...)
+}
+
+// RemoveMemberRoleContext is RemoveMemberRole with a context
+func (c *Cluster) RemoveMemberRoleContext(ctx context.Context, guildID, userID, roleID string, options ...rest.RequestOption) (err error) {
+	endpoint := rest.GuildMemberRole(guildID, userID, roleID)
+	err = c.Rest.DoWithOptions(ctx, http.MethodDelete, endpoint, nil, nil, options...)
+	return
+}
+
+// FetchEmojis fetches the custom emojis of a guild
+func (c *Cluster) FetchEmojis(guildID string) ([]*Emoji, error) {
+	return c.FetchEmojisContext(context.Background(), guildID)
+}
+
+// FetchEmojisContext is FetchEmojis with a context
+func (c *Cluster) FetchEmojisContext(ctx context.Context, guildID string) (emojis []*Emoji, err error) {
+	endpoint := rest.GuildEmojis(guildID)
+	err = c.Rest.DoContext(ctx, http.MethodGet, endpoint, nil, &emojis)
+	return
+}
+
+// CreateEmoji creates a custom emoji in a guild. The image is a data URI such as data:image/png;base64,...
+// and roles restricts the emoji to these roles when not empty
+func (c *Cluster) CreateEmoji(guildID, name, image string, roles []string, options ...rest.RequestOption) (*Emoji, error) {
+	return c.CreateEmojiContext(context.Background(), guildID, name, image, roles, options...)
+}
+
+// CreateEmojiContext is CreateEmoji with a context
+func (c *Cluster) CreateEmojiContext(ctx context.Context, guildID, name, image string, roles []string, options ...rest.RequestOption) (e *Emoji, err error) {
+	endpoint := rest.GuildEmojis(guildID)
+
+	body, err := json.Marshal(&struct {
+		Name  string   `json:"name"`
+		Image string   `json:"image"`
+		Roles []string `json:"roles,omitempty"`
+	}{name, image, roles})
+	if err != nil {
+		return
+	}
+
+	err = c.Rest.DoWithOptions(ctx, http.MethodPost, endpoint, body, &e, options...)
 	return
 }
@@ -0,0 +1,237 @@
+package gocord
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Soumil07/gocord/rest"
+	eventemitter "github.com/euskadi31/go-eventemitter"
+)
+
+// apiRequest is a request received by the test API
+type apiRequest struct {
+	*http.Request
+	Body string
+}
+
+// newTestAPI returns a cluster sending its REST requests to a server answering with the handler, and
+// the requests the server received
+func newTestAPI(t *testing.T, handler http.HandlerFunc) (*Cluster, *[]apiRequest) {
+	var requests []apiRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, apiRequest{r, string(body)})
+
+		if handler == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return &Cluster{
+		Emitter: eventemitter.New(),
+		Token:   "token",
+		Rest:    rest.NewRestManager("token", rest.WithBaseURL(server.URL)),
+	}, &requests
+}
+
+func TestBans(t *testing.T) {
+	c, requests := newTestAPI(t, nil)
+
+	t.Run("ban", func(t *testing.T) {
+		if err := c.BanMember("1", "2", "raiding the server", 7); err != nil {
+			t.Fatal(err)
+		}
+
+		req := (*requests)[len(*requests)-1]
+		if req.Method != http.MethodPut || req.URL.Path != "/v6/guilds/1/bans/2" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL.Path)
+		}
+		if req.Body != `{"delete_message_days":7}` {
+			t.Errorf("unexpected body: %s", req.Body)
+		}
+		if reason := req.Header.Get("X-Audit-Log-Reason"); reason != "raiding%20the%20server" {
+			t.Errorf("unexpected reason: %s", reason)
+		}
+	})
+
+	t.Run("unban", func(t *testing.T) {
+		if err := c.UnbanMember("1", "2", rest.WithReason("appealed")); err != nil {
+			t.Fatal(err)
+		}
+
+		req := (*requests)[len(*requests)-1]
+		if req.Method != http.MethodDelete || req.Header.Get("X-Audit-Log-Reason") != "appealed" {
+			t.Errorf("unexpected request: %s %v", req.Method, req.Header)
+		}
+	})
+}
+
+func TestGuildEndpoints(t *testing.T) {
+	c, requests := newTestAPI(t, nil)
+
+	permissions := 0
+	hoist := true
+	nick := "bob"
+	mute := false
+	voice := ""
+	timeout := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name   string
+		call   func() error
+		method string
+		path   string
+		body   string
+		reason string
+	}{
+		{
+			name:   "fetch roles",
+			call:   func() error { _, err := c.FetchRoles("1"); return err },
+			method: http.MethodGet,
+			path:   "/v6/guilds/1/roles",
+		},
+		{
+			name: "create role",
+			call: func() error {
+				_, err := c.CreateRole("1", CreateRole{Name: "muted", Permissions: &permissions}, rest.WithReason("spam"))
+				return err
+			},
+			method: http.MethodPost,
+			path:   "/v6/guilds/1/roles",
+			body:   `{"name":"muted","permissions":0}`,
+			reason: "spam",
+		},
+		{
+			name:   "modify role",
+			call:   func() error { _, err := c.ModifyRole("1", "2", ModifyRole{Hoist: &hoist}); return err },
+			method: http.MethodPatch,
+			path:   "/v6/guilds/1/roles/2",
+			body:   `{"hoist":true}`,
+		},
+		{
+			name:   "reorder roles",
+			call:   func() error { _, err := c.ModifyRolePositions("1", []RolePosition{{"2", 1}}); return err },
+			method: http.MethodPatch,
+			path:   "/v6/guilds/1/roles",
+			body:   `[{"id":"2","position":1}]`,
+		},
+		{
+			name:   "delete role",
+			call:   func() error { return c.DeleteRole("1", "2") },
+			method: http.MethodDelete,
+			path:   "/v6/guilds/1/roles/2",
+		},
+		{
+			name:   "add member role",
+			call:   func() error { return c.AddMemberRole("1", "3", "2", rest.WithReason("verified")) },
+			method: http.MethodPut,
+			path:   "/v6/guilds/1/members/3/roles/2",
+			reason: "verified",
+		},
+		{
+			name:   "remove member role",
+			call:   func() error { return c.RemoveMemberRole("1", "3", "2") },
+			method: http.MethodDelete,
+			path:   "/v6/guilds/1/members/3/roles/2",
+		},
+		{
+			name: "modify member",
+			call: func() error {
+				_, err := c.ModifyMember("1", "3", ModifyMember{Nick: &nick, Roles: []string{"2"}, Mute: &mute, ChannelID: &voice, TimeoutUntil: &timeout})
+				return err
+			},
+			method: http.MethodPatch,
+			path:   "/v6/guilds/1/members/3",
+			body:   `{"channel_id":null,"communication_disabled_until":"2021-01-02T03:04:05Z","mute":false,"nick":"bob","roles":["2"]}`,
+		},
+		{
+			name:   "end timeout",
+			call:   func() error { _, err := c.ModifyMember("1", "3", ModifyMember{TimeoutUntil: &time.Time{}}); return err },
+			method: http.MethodPatch,
+			path:   "/v6/guilds/1/members/3",
+			body:   `{"communication_disabled_until":null}`,
+		},
+		{
+			name:   "kick",
+			call:   func() error { return c.KickMember("1", "3", rest.WithReason("spam")) },
+			method: http.MethodDelete,
+			path:   "/v6/guilds/1/members/3",
+			reason: "spam",
+		},
+		{
+			name:   "fetch emojis",
+			call:   func() error { _, err := c.FetchEmojis("1"); return err },
+			method: http.MethodGet,
+			path:   "/v6/guilds/1/emojis",
+		},
+		{
+			name: "create emoji",
+			call: func() error {
+				_, err := c.CreateEmoji("1", "blob", "data:image/png;base64,AA==", []string{"2"})
+				return err
+			},
+			method: http.MethodPost,
+			path:   "/v6/guilds/1/emojis",
+			body:   `{"name":"blob","image":"data:image/png;base64,AA==","roles":["2"]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.call(); err != nil {
+				t.Fatal(err)
+			}
+
+			req := (*requests)[len(*requests)-1]
+			if req.Method != test.method || req.URL.Path != test.path {
+				t.Errorf("unexpected request: %s %s", req.Method, req.URL.Path)
+			}
+			if req.Body != test.body {
+				t.Errorf("unexpected body: %s", req.Body)
+			}
+			if reason := req.Header.Get("X-Audit-Log-Reason"); reason != test.reason {
+				t.Errorf("unexpected reason: %q", reason)
+			}
+		})
+	}
+}
+
+func TestGuildModels(t *testing.T) {
+	data := `{
+		"id": "1",
+		"roles": [{"id": "2", "name": "mod", "color": 3447003, "hoist": true, "position": 1, "permissions": 8, "managed": false, "mentionable": true}],
+		"emojis": [{"id": "4", "name": "blob", "roles": ["2"], "require_colons": true, "animated": true, "available": true}],
+		"members": [{"user": {"id": "3"}, "nick": "bob", "roles": ["2"], "joined_at": "2020-01-01T00:00:00+00:00", "deaf": true, "mute": false,
+			"communication_disabled_until": "2021-01-02T03:04:05+00:00"}],
+		"presences": [{"user": {"id": "3"}, "status": "idle", "client_status": {"mobile": "idle"},
+			"activities": [{"name": "Custom Status", "type": 4, "state": "busy", "emoji": {"name": "🔥"}}]}]
+	}`
+
+	var guild Guild
+	if err := json.Unmarshal([]byte(data), &guild); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(guild.Roles) != 1 || guild.Roles[0] != (Role{"2", "mod", 3447003, true, 1, PermissionsAdministrators, false, true}) {
+		t.Errorf("unexpected roles: %+v", guild.Roles)
+	}
+	if len(guild.Emojis) != 1 || guild.Emojis[0].ID != "4" || guild.Emojis[0].Roles[0] != "2" || !guild.Emojis[0].Animated {
+		t.Errorf("unexpected emojis: %+v", guild.Emojis)
+	}
+	if len(guild.Members) != 1 || guild.Members[0].User.ID != "3" || !guild.Members[0].Deaf || guild.Members[0].CommunicationDisabledUntil == "" {
+		t.Errorf("unexpected members: %+v", guild.Members)
+	}
+	if len(guild.Presences) != 1 {
+		t.Fatalf("unexpected presences: %+v", guild.Presences)
+	}
+	presence := guild.Presences[0]
+	if presence.Status != "idle" || presence.ClientStatus.Mobile != "idle" || len(presence.Activities) != 1 || presence.Activities[0].Emoji.Name != "🔥" {
+		t.Errorf("unexpected presence: %+v", presence)
+	}
+}
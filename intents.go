@@ -0,0 +1,95 @@
+package gocord
+
+// Contains gateway intents, and helpers to work out the intents required by the subscribed events
+
+// Intents is a bitfield of gateway intents, deciding which events are sent to a shard
+type Intents int
+
+// Gateway intents, as documented at https://discordapp.com/developers/docs/topics/gateway#gateway-intents
+const (
+	IntentsGuilds       Intents = 1 << iota
+	IntentsGuildMembers         // privileged
+	IntentsGuildBans
+	IntentsGuildEmojis
+	IntentsGuildIntegrations
+	IntentsGuildWebhooks
+	IntentsGuildInvites
+	IntentsGuildVoiceStates
+	IntentsGuildPresences // privileged
+	IntentsGuildMessages
+	IntentsGuildMessageReactions
+	IntentsGuildMessageTyping
+	IntentsDirectMessages
+	IntentsDirectMessageReactions
+	IntentsDirectMessageTyping
+	IntentsMessageContent // privileged
+)
+
+const (
+	// IntentsPrivileged are the intents that must be enabled for the application in the developer portal
+	IntentsPrivileged = IntentsGuildMembers | IntentsGuildPresences | IntentsMessageContent
+	// IntentsAll are all intents
+	IntentsAll = IntentsMessageContent<<1 - 1
+	// IntentsNonPrivileged are all intents that are not privileged
+	IntentsNonPrivileged = IntentsAll &^ IntentsPrivileged
+)
+
+// eventIntents maps gateway events to the intents they are sent with
+var eventIntents = map[string]Intents{
+	GuildCreateEvent:                IntentsGuilds,
+	GuildUpdateEvent:                IntentsGuilds,
+	GuildDeleteEvent:                IntentsGuilds,
+	GuildRoleCreateEvent:            IntentsGuilds,
+	GuildRoleUpdateEvent:            IntentsGuilds,
+	GuildRoleDeleteEvent:            IntentsGuilds,
+	ChannelCreateEvent:              IntentsGuilds,
+	ChannelUpdateEvent:              IntentsGuilds,
+	ChannelDeleteEvent:              IntentsGuilds,
+	ChannelPinsUpdateEvent:          IntentsGuilds | IntentsDirectMessages,
+	GuildMemberAddEvent:             IntentsGuildMembers,
+	GuildMemberUpdateEvent:          IntentsGuildMembers,
+	GuildMemberRemoveEvent:          IntentsGuildMembers,
+	GuildBanAddEvent:                IntentsGuildBans,
+	GuildBanRemoveEvent:             IntentsGuildBans,
+	GuildEmojisUpdateEvent:          IntentsGuildEmojis,
+	GuildIntegrationsUpdateEvent:    IntentsGuildIntegrations,
+	WebhooksUpdateEvent:             IntentsGuildWebhooks,
+	InviteCreateEvent:               IntentsGuildInvites,
+	InviteDeleteEvent:               IntentsGuildInvites,
+	VoiceStateUpdateEvent:           IntentsGuildVoiceStates,
+	PresenceUpdateEvent:             IntentsGuildPresences,
+	MessageEvent:                    IntentsGuildMessages | IntentsDirectMessages,
+	MessageUpdateEvent:              IntentsGuildMessages | IntentsDirectMessages,
+	MessageDeleteEvent:              IntentsGuildMessages | IntentsDirectMessages,
+	MessageDeleteBulkEvent:          IntentsGuildMessages,
+	MessageReactionAddEvent:         IntentsGuildMessageReactions | IntentsDirectMessageReactions,
+	MessageReactionRemoveEvent:      IntentsGuildMessageReactions | IntentsDirectMessageReactions,
+	MessageReactionRemoveAllEvent:   IntentsGuildMessageReactions | IntentsDirectMessageReactions,
+	MessageReactionRemoveEmojiEvent: IntentsGuildMessageReactions | IntentsDirectMessageReactions,
+	TypingStartEvent:                IntentsGuildMessageTyping | IntentsDirectMessageTyping,
+}
+
+// emitterEvents maps the names events are dispatched to the cluster with to gateway events
+var emitterEvents = map[string]string{
+	"guildCreate": GuildCreateEvent,
+	"message":     MessageEvent,
+}
+
+// Has checks if the bitfield has all the supplied intents
+func (i Intents) Has(intents Intents) bool {
+	return i&intents == intents
+}
+
+// IntentsForEvents returns the intents required to receive the supplied events. Both gateway event
+// names and the names events are dispatched to the cluster with are accepted. NOTE: IntentsMessageContent
+// is never included, add it yourself if the content of messages is needed
+func IntentsForEvents(events ...string) (intents Intents) {
+	for _, event := range events {
+		if name, ok := emitterEvents[event]; ok {
+			event = name
+		}
+		intents |= eventIntents[event]
+	}
+
+	return
+}
@@ -0,0 +1,54 @@
+package gocord
+
+import "testing"
+
+func TestIntents(t *testing.T) {
+	t.Run("events", func(t *testing.T) {
+		intents := IntentsForEvents("message", GuildMemberAddEvent, TypingStartEvent)
+		expected := IntentsGuildMessages | IntentsDirectMessages | IntentsGuildMembers |
+			IntentsGuildMessageTyping | IntentsDirectMessageTyping
+		if intents != expected {
+			t.Errorf("expected %d, received %d", expected, intents)
+		}
+	})
+
+	t.Run("subscribed events", func(t *testing.T) {
+		c := newTestCluster("")
+		c.Subscribe("guildCreate", func(g Guild) {})
+		c.Subscribe("ready", func(s *Shard) {})
+		if expected := IntentsGuilds | IntentsGuildVoiceStates; c.RequiredIntents() != expected {
+			t.Errorf("expected %d, received %d", expected, c.RequiredIntents())
+		}
+
+		c.Options.Intents = IntentsAll
+		if c.intents() != IntentsAll {
+			t.Errorf("explicit intents should be used")
+		}
+	})
+
+	t.Run("library intents", func(t *testing.T) {
+		// the guild cache, voice joins and chunking depend on events no handler is subscribed to
+		c := newTestCluster("")
+		c.OnMessageCreate(func(s *Shard, m *MessageCreate) {})
+		intents := c.intents()
+		if !intents.Has(IntentsGuildMessages|IntentsGuilds|IntentsGuildVoiceStates) || intents.Has(IntentsGuildMembers) {
+			t.Errorf("unexpected intents %d", intents)
+		}
+
+		c.Options.ChunkGuilds = true
+		if !c.intents().Has(IntentsGuildMembers) {
+			t.Error("chunking guilds requires the guild members intent")
+		}
+
+		c.Options.Intents = IntentsGuildMessages
+		if !c.intents().Has(IntentsGuilds | IntentsGuildVoiceStates | IntentsGuildMembers) {
+			t.Errorf("the library intents were not added to explicit intents: %d", c.intents())
+		}
+	})
+
+	t.Run("privileged", func(t *testing.T) {
+		if IntentsNonPrivileged.Has(IntentsGuildPresences) || !IntentsAll.Has(IntentsMessageContent) {
+			t.Fail()
+		}
+	})
+}
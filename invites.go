@@ -1,6 +1,7 @@
 package gocord
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 
@@ -15,7 +16,12 @@ type Invite struct {
 	MemberCount   int      `json:"approximate_member_count"`
 }
 
-func (c *Cluster) FetchInvite(code string, withCounts bool) (i *Invite, err error) {
+func (c *Cluster) FetchInvite(code string, withCounts bool) (*Invite, error) {
+	return c.FetchInviteContext(context.Background(), code, withCounts)
+}
+
+// FetchInviteContext is FetchInvite with a context
+func (c *Cluster) FetchInviteContext(ctx context.Context, code string, withCounts bool) (i *Invite, err error) {
 	endpoint := rest.Invite(code)
 	body, err := json.Marshal(&struct {
 		WithCounts bool `json:"with_counts"`
@@ -25,12 +31,17 @@ func (c *Cluster) FetchInvite(code string, withCounts bool) (i *Invite, err erro
 		return
 	}
 
-	err = c.Rest.Do(http.MethodGet, endpoint, body, &i)
+	err = c.Rest.DoContext(ctx, http.MethodGet, endpoint, body, &i)
 	return
 }
 
-func (c *Cluster) DeleteInvite(code string) (i *Invite, err error) {
+func (c *Cluster) DeleteInvite(code string) (*Invite, error) {
+	return c.DeleteInviteContext(context.Background(), code)
+}
+
+// DeleteInviteContext is DeleteInvite with a context
+func (c *Cluster) DeleteInviteContext(ctx context.Context, code string) (i *Invite, err error) {
 	endpoint := rest.Invite(code)
-	err = c.Rest.Do(http.MethodDelete, endpoint, nil, &i)
+	err = c.Rest.DoContext(ctx, http.MethodDelete, endpoint, nil, &i)
 	return
 }
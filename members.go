@@ -0,0 +1,185 @@
+package gocord
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Implements requesting guild members through the gateway, and gathering the chunks sent in response
+
+// DefaultMemberRequestTimeout is the time to wait for every chunk of a member request when
+// ClusterOptions.MemberRequestTimeout is 0
+const DefaultMemberRequestTimeout = 30 * time.Second
+
+// ErrMemberRequestTimeout is returned when the chunks of a member request aren't all received in time
+var ErrMemberRequestTimeout = errors.New("timed out waiting for guild member chunks")
+
+var nonceCounter uint64
+
+// GuildMembers is the result of a member request, gathered from every chunk
+type GuildMembers struct {
+	GuildID   string
+	Members   []Member
+	Presences []GuildMemberPresence
+	NotFound  []string // the requested user IDs that are not in the guild
+}
+
+// memberRequest is a pending member request, waiting for its chunks
+type memberRequest struct {
+	sync.Mutex
+	result   *GuildMembers
+	received int           // the number of chunks received
+	done     chan struct{} // closed once every chunk is received
+}
+
+type requestGuildMembersPayload struct {
+	GuildID   string   `json:"guild_id"`
+	Query     *string  `json:"query,omitempty"`
+	Limit     int      `json:"limit"`
+	Presences bool     `json:"presences,omitempty"`
+	UserIDs   []string `json:"user_ids,omitempty"`
+	Nonce     string   `json:"nonce"`
+}
+
+// RequestGuildMembers requests the members of a guild whose username starts with the query, or the members
+// with the supplied user IDs, and blocks until every chunk is received. An empty query and a limit of 0
+// requests all members, requiring the guild members intent. The members received are written to the cache
+func (s *Shard) RequestGuildMembers(guildID, query string, limit int, userIDs []string, presences bool) (*GuildMembers, error) {
+	nonce := fmt.Sprintf("%d-%d", s.ID, atomic.AddUint64(&nonceCounter, 1))
+	request := &memberRequest{
+		result: &GuildMembers{GuildID: guildID},
+		done:   make(chan struct{}),
+	}
+	s.memberRequests.Store(nonce, request)
+	defer s.memberRequests.Delete(nonce)
+
+	payload := requestGuildMembersPayload{
+		GuildID:   guildID,
+		Limit:     limit,
+		Presences: presences,
+		UserIDs:   userIDs,
+		Nonce:     nonce,
+	}
+	// query and user_ids are mutually exclusive
+	if len(userIDs) == 0 {
+		payload.Query = &query
+	}
+
+	err := s.sendLimited(OPCodeRequestGuildMembers, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := s.Cluster.Options.MemberRequestTimeout
+	if timeout == 0 {
+		timeout = DefaultMemberRequestTimeout
+	}
+
+	select {
+	case <-request.done:
+		return request.result, nil
+	case <-time.After(timeout):
+		// the chunks received so far are still returned
+		s.memberRequests.Delete(nonce)
+
+		request.Lock()
+		defer request.Unlock()
+		result := *request.result
+		return &result, ErrMemberRequestTimeout
+	}
+}
+
+// onMembersChunk writes the members of a chunk to the cache, and adds them to the pending request
+func (s *Shard) onMembersChunk(chunk *GuildMembersChunk) {
+	if cached, ok := s.GuildCache.Get(chunk.GuildID); ok {
+		s.membersMu.Lock()
+		cached.(*Guild).addMembers(chunk.Members)
+		s.membersMu.Unlock()
+	}
+
+	if chunk.Nonce == "" {
+		return
+	}
+	v, ok := s.memberRequests.Load(chunk.Nonce)
+	if !ok {
+		return
+	}
+
+	request := v.(*memberRequest)
+	request.Lock()
+	defer request.Unlock()
+
+	request.result.Members = append(request.result.Members, chunk.Members...)
+	request.result.Presences = append(request.result.Presences, chunk.Presences...)
+	request.result.NotFound = append(request.result.NotFound, chunk.NotFound...)
+
+	// chunks are sent in order, the request is complete once chunk_index == chunk_count - 1. The chunks
+	// are counted instead, in case they are ever reordered
+	request.received++
+	if request.received == chunk.ChunkCount {
+		s.memberRequests.Delete(chunk.Nonce)
+		close(request.done)
+	}
+}
+
+// chunkGuild requests every member of a large guild, which only includes online members in GUILD_CREATE.
+// Guilds are chunked once per session, the requests waiting for the send limit of the connection
+func (s *Shard) chunkGuild(guild *Guild) {
+	if !s.Cluster.Options.ChunkGuilds || !guild.Large {
+		return
+	}
+	if _, chunked := s.chunkedGuilds.LoadOrStore(guild.ID, struct{}{}); chunked {
+		return
+	}
+
+	go func() {
+		s.debugf("requesting the members of %s", guild.Name)
+		_, err := s.RequestGuildMembers(guild.ID, "", 0, nil, false)
+		if err != nil {
+			// the guild is chunked again on its next GUILD_CREATE
+			s.chunkedGuilds.Delete(guild.ID)
+			s.debugf("failed to request the members of %s: %s", guild.Name, err)
+		}
+	}()
+}
+
+// CachedMembers returns a copy of the members of a cached guild. Member chunks add to the members of cached
+// guilds while the shard runs, so they are read through CachedMembers rather than Guild.Members
+func (s *Shard) CachedMembers(guildID string) []Member {
+	cached, ok := s.GuildCache.Get(guildID)
+	if !ok {
+		return nil
+	}
+
+	s.membersMu.RLock()
+	defer s.membersMu.RUnlock()
+
+	return append([]Member(nil), cached.(*Guild).Members...)
+}
+
+// addMembers adds members to the guild, replacing the members already present. The members of cached guilds
+// are guarded by Shard.membersMu
+func (g *Guild) addMembers(members []Member) {
+	index := make(map[string]int, len(g.Members))
+	for i, member := range g.Members {
+		if member.User != nil {
+			index[member.User.ID] = i
+		}
+	}
+
+	for _, member := range members {
+		if member.User == nil {
+			continue
+		}
+		if i, ok := index[member.User.ID]; ok {
+			g.Members[i] = member
+			continue
+		}
+
+		index[member.User.ID] = len(g.Members)
+		g.Members = append(g.Members, member)
+	}
+}
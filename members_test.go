@@ -0,0 +1,174 @@
+package gocord
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRequestGuildMembers(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		ws.WriteJSON(map[string]interface{}{"op": OPCodeHello, "d": map[string]interface{}{"heartbeat_interval": 60000}})
+		for {
+			var payload receivePayload
+			if err := ws.ReadJSON(&payload); err != nil {
+				return
+			}
+
+			switch payload.OP {
+			case OPCodeIdentify:
+				ws.WriteJSON(map[string]interface{}{"op": OPCodeDispatch, "s": 1, "t": ReadyEvent, "d": map[string]interface{}{
+					"session_id": "session",
+					"guilds":     []interface{}{map[string]interface{}{"id": "1", "unavailable": true}},
+				}})
+			case OPCodeRequestGuildMembers:
+				var request requestGuildMembersPayload
+				json.Unmarshal(payload.D, &request)
+				for i := 0; i < 2; i++ {
+					ws.WriteJSON(map[string]interface{}{"op": OPCodeDispatch, "t": GuildMembersChunkEvent, "d": map[string]interface{}{
+						"guild_id":    request.GuildID,
+						"nonce":       request.Nonce,
+						"chunk_index": i,
+						"chunk_count": 2,
+						"members":     []interface{}{map[string]interface{}{"user": map[string]interface{}{"id": request.UserIDs[i]}}},
+					}})
+				}
+			}
+		}
+	}))
+	defer server.Close()
+
+	cluster := newTestCluster("ws" + strings.TrimPrefix(server.URL, "http"))
+	ready := make(chan struct{})
+	cluster.OnReady(func(s *Shard, r *Ready) {
+		close(ready)
+	})
+
+	shard := NewShard(0, cluster)
+	go shard.Connect()
+	defer shard.Close()
+
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the shard did not connect")
+	}
+
+	members, err := shard.RequestGuildMembers("1", "", 0, []string{"10", "11"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members.Members) != 2 || members.Members[0].User.ID != "10" || members.Members[1].User.ID != "11" {
+		t.Errorf("unexpected members: %#v", members.Members)
+	}
+
+	if len(shard.CachedMembers("1")) != 2 {
+		t.Errorf("the members were not cached")
+	}
+}
+
+func TestChunkGuild(t *testing.T) {
+	ready := map[string]interface{}{
+		"session_id": "session",
+		"guilds":     []interface{}{map[string]interface{}{"id": "1", "unavailable": true}},
+	}
+	guild := func(memberID string) map[string]interface{} {
+		return map[string]interface{}{"op": OPCodeDispatch, "t": GuildCreateEvent, "d": map[string]interface{}{
+			"id":      "1",
+			"large":   true,
+			"members": []interface{}{map[string]interface{}{"user": map[string]interface{}{"id": memberID}}},
+		}}
+	}
+
+	var requests, updates int32
+	server := newTestGateway(ready, func(ws *websocket.Conn, payload *receivePayload) {
+		switch payload.OP {
+		// every presence update makes the gateway send the guild, the second time after an outage
+		case OPCodeStatusUpdate:
+			if atomic.AddInt32(&updates, 1) == 2 {
+				ws.WriteJSON(map[string]interface{}{"op": OPCodeDispatch, "t": GuildDeleteEvent, "d": map[string]interface{}{"id": "1", "unavailable": true}})
+				ws.WriteJSON(guild("12"))
+				return
+			}
+			ws.WriteJSON(guild("10"))
+
+		case OPCodeRequestGuildMembers:
+			atomic.AddInt32(&requests, 1)
+
+			var request requestGuildMembersPayload
+			json.Unmarshal(payload.D, &request)
+			ws.WriteJSON(map[string]interface{}{"op": OPCodeDispatch, "t": GuildMembersChunkEvent, "d": map[string]interface{}{
+				"guild_id":    request.GuildID,
+				"nonce":       request.Nonce,
+				"chunk_index": 0,
+				"chunk_count": 1,
+				"members": []interface{}{
+					map[string]interface{}{"user": map[string]interface{}{"id": "10"}},
+					map[string]interface{}{"user": map[string]interface{}{"id": "11"}},
+				},
+			}})
+		}
+	})
+	defer server.Close()
+
+	shard := connectTestShard(t, server)
+	defer shard.Close()
+	shard.Cluster.Options.ChunkGuilds = true
+
+	waitMembers := func(count int) {
+		deadline := time.Now().Add(5 * time.Second)
+		for len(shard.CachedMembers("1")) != count {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d cached members, got %d", count, len(shard.CachedMembers("1")))
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	shard.UpdateGame("chunking")
+	waitMembers(2)
+
+	// the members requested before the outage are kept, and the guild isn't chunked again
+	shard.UpdateGame("outage")
+	waitMembers(3)
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected a single member request, got %d", n)
+	}
+}
+
+func TestSendLimiter(t *testing.T) {
+	var l sendLimiter
+	for i := 0; i < gatewaySendLimit-reservedSends; i++ {
+		if !l.wait(nil) {
+			t.Fatal("a send within the limit was held up")
+		}
+	}
+
+	// the next send waits for the window to end
+	l.resetAt = time.Now().Add(50 * time.Millisecond)
+	start := time.Now()
+	if !l.wait(nil) || time.Since(start) < 40*time.Millisecond {
+		t.Errorf("the send didn't wait for the next window, waited %s", time.Since(start))
+	}
+
+	l.remaining = 0
+	l.resetAt = time.Now().Add(time.Minute)
+	stop := make(chan struct{})
+	close(stop)
+	if l.wait(stop) {
+		t.Error("expected the wait to be stopped")
+	}
+}
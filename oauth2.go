@@ -7,6 +7,9 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
+
+	"github.com/Soumil07/gocord/rest"
 )
 
 // Implements Oauth2 helper methods and definitions. This package cannot be used standalone, and requires a website or
@@ -17,6 +20,8 @@ type Oauth2Application struct {
 	ClientID     string
 	ClientSecret string
 	Scope        string
+	BaseURL      string       // the URL requests are sent to, including the API version. Discord's when empty
+	HTTPClient   *http.Client // the client requests are sent with, http.DefaultClient when nil
 }
 
 // Oauth2Callback is a struct of data returned in the querystring params during Oauth flow
@@ -43,10 +48,31 @@ func NewOauth2Application(clientID, clientSecret, scope string) *Oauth2Applicati
 	}
 }
 
+// url returns the URL of an API path
+func (o *Oauth2Application) url(path string) string {
+	if o.BaseURL == "" {
+		return fmt.Sprintf("%s/v%d%s", rest.DefaultBaseURL, rest.DefaultAPIVersion, path)
+	}
+
+	return strings.TrimSuffix(o.BaseURL, "/") + path
+}
+
+// client returns the HTTP client requests are sent with
+func (o *Oauth2Application) client() *http.Client {
+	if o.HTTPClient == nil {
+		return http.DefaultClient
+	}
+
+	return o.HTTPClient
+}
+
 // Callback generates an access_token from the supplied querystring parameters. Use this with the querystring parameters
 // sent in the redirect url
 func (o *Oauth2Application) Callback(obj Oauth2Callback) (*AccessTokenResponse, error) {
-	parsed, _ := url.Parse("https://discordapp.com/api/oauth2/token")
+	parsed, err := url.Parse(o.url("/oauth2/token"))
+	if err != nil {
+		return nil, err
+	}
 	query := parsed.Query()
 	query.Set("grant_type", "authorization_code")
 	query.Set("code", obj.Code)
@@ -61,7 +87,7 @@ func (o *Oauth2Application) Callback(obj Oauth2Callback) (*AccessTokenResponse,
 	req.Header.Add("Authorization", basicAuth(o.ClientID, o.ClientSecret))
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := o.client().Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -76,13 +102,13 @@ func (o *Oauth2Application) Callback(obj Oauth2Callback) (*AccessTokenResponse,
 // User returns the current authenticated user given the supplied access token
 func (o *Oauth2Application) User(accessToken string) (u *User, err error) {
 	// TODO: check scope
-	req, err := http.NewRequest(http.MethodGet, "https://discordapp.com/api/v6/users/@me", nil)
+	req, err := http.NewRequest(http.MethodGet, o.url("/users/@me"), nil)
 	if err != nil {
 		return
 	}
 
 	req.Header.Add("Authorization", "Bearer "+accessToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := o.client().Do(req)
 	if err != nil {
 		return
 	}
@@ -100,13 +126,13 @@ func (o *Oauth2Application) User(accessToken string) (u *User, err error) {
 
 // Guilds returns an array of guilds the authenticated user is in
 func (o *Oauth2Application) Guilds(accessToken string) (guilds []*Guild, err error) {
-	req, err := http.NewRequest(http.MethodGet, "https://discordapp.com/api/v6/users/@me/guilds", nil)
+	req, err := http.NewRequest(http.MethodGet, o.url("/users/@me/guilds"), nil)
 	if err != nil {
 		return
 	}
 
 	req.Header.Add("Authorization", "Bearer "+accessToken)
-	res, err := http.DefaultClient.Do(req)
+	res, err := o.client().Do(req)
 	if err != nil {
 		return
 	}
@@ -1,6 +1,10 @@
 package gocord
 
-import "testing"
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
 
 func TestBasicAuth(t *testing.T) {
 	var username = "stitch"
@@ -11,3 +15,25 @@ func TestBasicAuth(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestOauth2BaseURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v6/users/@me" || r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"id": "1", "username": "gocord"}`))
+	}))
+	defer server.Close()
+
+	app := NewOauth2Application("id", "secret", "identify")
+	app.BaseURL = server.URL + "/api/v6/"
+
+	user, err := app.User("access")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != "1" {
+		t.Errorf("unexpected user: %#v", user)
+	}
+}
@@ -0,0 +1,252 @@
+package gocord
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+
+	"github.com/Soumil07/gocord/rest"
+)
+
+// Implements iterating over paginated endpoints, a batch per request
+
+// The most items a single request returns, for each paginated endpoint
+const (
+	maxMessagesPerRequest  = 100
+	maxReactionsPerRequest = 100
+	maxMembersPerRequest   = 1000
+	maxBansPerRequest      = 1000
+)
+
+// Ban represents the ban of a user from a guild
+type Ban struct {
+	Reason string `json:"reason"`
+	User   *User  `json:"user"`
+}
+
+// pager requests the pages of an endpoint, moving a before, after or around cursor from page to page. Rate
+// limits are waited for by the rest manager, and the pager stops at the first error, such as the context
+// being done or a rate limit exceeding the retry policy
+type pager struct {
+	ctx      context.Context
+	rest     *rest.RestManager
+	endpoint string
+	cursor   string // before, after or around
+	id       string // the ID the cursor points to, empty for the default one
+	max      int    // the most items a request returns
+	limit    int    // the items left to fetch, negative for all of them
+	fetched  bool   // whether a page was fetched
+	done     bool
+	err      error
+}
+
+// newPager returns a pager fetching up to limit items, or every item when limit is 0
+func newPager(ctx context.Context, c *Cluster, endpoint, cursor, id string, max, limit int) pager {
+	if limit <= 0 {
+		limit = -1
+	}
+
+	return pager{ctx: ctx, rest: c.Rest, endpoint: endpoint, cursor: cursor, id: id, max: max, limit: limit}
+}
+
+// next decodes the next page into page, a pointer to a slice. id returns the ID of an item of the page,
+// the next cursor being the lowest ID before the page and the highest after it. It returns false once
+// every page was fetched or a request failed
+func (p *pager) next(page interface{}, id func(i int) string) bool {
+	if p.done || p.err != nil || p.limit == 0 {
+		return false
+	}
+
+	requested := p.max
+	if p.limit > 0 && p.limit < requested {
+		requested = p.limit
+	}
+
+	query := url.Values{"limit": {strconv.Itoa(requested)}}
+	if p.id != "" {
+		query.Set(p.cursor, p.id)
+	}
+
+	p.err = p.rest.DoContext(p.ctx, http.MethodGet, p.endpoint+"?"+query.Encode(), nil, page)
+	if p.err != nil {
+		return false
+	}
+
+	n := reflect.ValueOf(page).Elem().Len()
+	previous := p.id
+	for i := 0; i < n; i++ {
+		item := id(i)
+		if p.id == "" || (p.cursor == "before") == snowflakeLess(item, p.id) {
+			p.id = item
+		}
+	}
+	if p.limit > 0 {
+		p.limit -= n
+		if p.limit < 0 {
+			p.limit = 0
+		}
+	}
+
+	// a short page is the last one, and so is a page ignoring the limit or a page around a message. A page
+	// not moving the cursor ignored it, such as bans on API versions without paginated bans, and repeats
+	// the previous page
+	p.done = n != requested || p.cursor == "around" || p.id == previous
+	if p.id == previous && p.fetched {
+		return false
+	}
+	p.fetched = true
+	return n > 0
+}
+
+// Err returns the error which stopped the iteration, nil if every page was fetched
+func (p *pager) Err() error {
+	return p.err
+}
+
+// MessageIterator iterates over the messages of a channel
+type MessageIterator struct {
+	pager
+	batch []*Message
+}
+
+// Next fetches the next batch of messages, returning false once there are none left or a request failed
+func (it *MessageIterator) Next() bool {
+	it.batch = nil
+	return it.next(&it.batch, func(i int) string { return it.batch[i].ID })
+}
+
+// Batch returns the messages fetched by the last call to Next, newest first
+func (it *MessageIterator) Batch() []*Message {
+	return it.batch
+}
+
+// MessagesBefore iterates over up to limit messages of a channel sent before a message, from the newest to
+// the oldest. An empty message ID starts from the latest message, and a limit of 0 walks the whole history
+func (c *Cluster) MessagesBefore(channelID, before string, limit int) *MessageIterator {
+	return c.MessagesBeforeContext(context.Background(), channelID, before, limit)
+}
+
+// MessagesBeforeContext is MessagesBefore with a context, stopping the iteration once the context is done
+func (c *Cluster) MessagesBeforeContext(ctx context.Context, channelID, before string, limit int) *MessageIterator {
+	endpoint := rest.ChannelMessages(channelID)
+	return &MessageIterator{pager: newPager(ctx, c, endpoint, "before", before, maxMessagesPerRequest, limit)}
+}
+
+// MessagesAfter iterates over up to limit messages of a channel sent after a message, from the oldest to
+// the newest batch. An empty message ID starts from the first message, and a limit of 0 fetches every message
+func (c *Cluster) MessagesAfter(channelID, after string, limit int) *MessageIterator {
+	return c.MessagesAfterContext(context.Background(), channelID, after, limit)
+}
+
+// MessagesAfterContext is MessagesAfter with a context, stopping the iteration once the context is done
+func (c *Cluster) MessagesAfterContext(ctx context.Context, channelID, after string, limit int) *MessageIterator {
+	if after == "" {
+		after = "0"
+	}
+
+	endpoint := rest.ChannelMessages(channelID)
+	return &MessageIterator{pager: newPager(ctx, c, endpoint, "after", after, maxMessagesPerRequest, limit)}
+}
+
+// MessagesAround fetches up to limit messages of a channel around a message in a single batch, the limit
+// being capped to 100
+func (c *Cluster) MessagesAround(channelID, around string, limit int) *MessageIterator {
+	return c.MessagesAroundContext(context.Background(), channelID, around, limit)
+}
+
+// MessagesAroundContext is MessagesAround with a context
+func (c *Cluster) MessagesAroundContext(ctx context.Context, channelID, around string, limit int) *MessageIterator {
+	endpoint := rest.ChannelMessages(channelID)
+	return &MessageIterator{pager: newPager(ctx, c, endpoint, "around", around, maxMessagesPerRequest, limit)}
+}
+
+// MemberIterator iterates over the members of a guild
+type MemberIterator struct {
+	pager
+	batch []*Member
+}
+
+// Next fetches the next batch of members, returning false once there are none left or a request failed
+func (it *MemberIterator) Next() bool {
+	it.batch = nil
+	return it.next(&it.batch, func(i int) string { return it.batch[i].User.ID })
+}
+
+// Batch returns the members fetched by the last call to Next, sorted by user ID
+func (it *MemberIterator) Batch() []*Member {
+	return it.batch
+}
+
+// MembersAfter iterates over up to limit members of a guild whose user ID is after a user, requiring the
+// guild members intent. An empty user ID starts from the first member, and a limit of 0 fetches every member
+func (c *Cluster) MembersAfter(guildID, after string, limit int) *MemberIterator {
+	return c.MembersAfterContext(context.Background(), guildID, after, limit)
+}
+
+// MembersAfterContext is MembersAfter with a context, stopping the iteration once the context is done
+func (c *Cluster) MembersAfterContext(ctx context.Context, guildID, after string, limit int) *MemberIterator {
+	endpoint := rest.GuildMembers(guildID)
+	return &MemberIterator{pager: newPager(ctx, c, endpoint, "after", after, maxMembersPerRequest, limit)}
+}
+
+// BanIterator iterates over the bans of a guild
+type BanIterator struct {
+	pager
+	batch []*Ban
+}
+
+// Next fetches the next batch of bans, returning false once there are none left or a request failed
+func (it *BanIterator) Next() bool {
+	it.batch = nil
+	return it.next(&it.batch, func(i int) string { return it.batch[i].User.ID })
+}
+
+// Batch returns the bans fetched by the last call to Next
+func (it *BanIterator) Batch() []*Ban {
+	return it.batch
+}
+
+// BansAfter iterates over up to limit bans of a guild whose user ID is after a user. An empty user ID starts
+// from the first ban, and a limit of 0 fetches every ban. API versions without paginated bans return every
+// ban in a single batch
+func (c *Cluster) BansAfter(guildID, after string, limit int) *BanIterator {
+	return c.BansAfterContext(context.Background(), guildID, after, limit)
+}
+
+// BansAfterContext is BansAfter with a context, stopping the iteration once the context is done
+func (c *Cluster) BansAfterContext(ctx context.Context, guildID, after string, limit int) *BanIterator {
+	endpoint := rest.GuildBans(guildID)
+	return &BanIterator{pager: newPager(ctx, c, endpoint, "after", after, maxBansPerRequest, limit)}
+}
+
+// UserIterator iterates over users, such as the users who reacted to a message
+type UserIterator struct {
+	pager
+	batch []*User
+}
+
+// Next fetches the next batch of users, returning false once there are none left or a request failed
+func (it *UserIterator) Next() bool {
+	it.batch = nil
+	return it.next(&it.batch, func(i int) string { return it.batch[i].ID })
+}
+
+// Batch returns the users fetched by the last call to Next, sorted by ID
+func (it *UserIterator) Batch() []*User {
+	return it.batch
+}
+
+// ReactionsAfter iterates over up to limit users who reacted to a message with an emoji, whose ID is after
+// a user. The emoji is either name:id for custom emojis or the URL encoded unicode emoji. An empty user ID
+// starts from the first user, and a limit of 0 fetches every user
+func (c *Cluster) ReactionsAfter(channelID, messageID, emoji, after string, limit int) *UserIterator {
+	return c.ReactionsAfterContext(context.Background(), channelID, messageID, emoji, after, limit)
+}
+
+// ReactionsAfterContext is ReactionsAfter with a context, stopping the iteration once the context is done
+func (c *Cluster) ReactionsAfterContext(ctx context.Context, channelID, messageID, emoji, after string, limit int) *UserIterator {
+	endpoint := rest.ChannelMessageReaction(channelID, messageID, emoji)
+	return &UserIterator{pager: newPager(ctx, c, endpoint, "after", after, maxReactionsPerRequest, limit)}
+}
@@ -0,0 +1,217 @@
+package gocord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/Soumil07/gocord/rest"
+)
+
+// serveMessages answers message history requests for a channel holding the messages 1 to count, newest first
+func serveMessages(count int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		limit, _ := strconv.Atoi(query.Get("limit"))
+
+		var messages []Message
+		if before := query.Get("before"); before != "" || query.Get("after") == "" {
+			from := count
+			if before != "" {
+				from, _ = strconv.Atoi(before)
+				from--
+			}
+			for id := from; id > 0 && len(messages) < limit; id-- {
+				messages = append(messages, Message{ID: strconv.Itoa(id)})
+			}
+		} else {
+			after, _ := strconv.Atoi(query.Get("after"))
+			to := after + limit
+			if to > count {
+				to = count
+			}
+			for id := to; id > after; id-- {
+				messages = append(messages, Message{ID: strconv.Itoa(id)})
+			}
+		}
+
+		json.NewEncoder(w).Encode(messages)
+	}
+}
+
+func TestMessageIterator(t *testing.T) {
+	tests := []struct {
+		name    string
+		iterate func(c *Cluster) *MessageIterator
+		queries []string
+		first   string // the first message fetched
+		last    string // the last message fetched
+		total   int
+	}{
+		{
+			name:    "whole history",
+			iterate: func(c *Cluster) *MessageIterator { return c.MessagesBefore("1", "", 0) },
+			queries: []string{"limit=100", "before=151&limit=100", "before=51&limit=100"},
+			first:   "250",
+			last:    "1",
+			total:   250,
+		},
+		{
+			name:    "before with a limit",
+			iterate: func(c *Cluster) *MessageIterator { return c.MessagesBefore("1", "200", 120) },
+			queries: []string{"before=200&limit=100", "before=100&limit=20"},
+			first:   "199",
+			last:    "80",
+			total:   120,
+		},
+		{
+			name:    "after",
+			iterate: func(c *Cluster) *MessageIterator { return c.MessagesAfter("1", "", 0) },
+			queries: []string{"after=0&limit=100", "after=100&limit=100", "after=200&limit=100"},
+			first:   "100",
+			last:    "201",
+			total:   250,
+		},
+		{
+			name:    "around",
+			iterate: func(c *Cluster) *MessageIterator { return c.MessagesAround("1", "50", 300) },
+			queries: []string{"around=50&limit=100"},
+			first:   "250",
+			last:    "151",
+			total:   100,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, requests := newTestAPI(t, serveMessages(250))
+
+			var messages []*Message
+			it := test.iterate(c)
+			for it.Next() {
+				messages = append(messages, it.Batch()...)
+			}
+			if err := it.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(*requests) != len(test.queries) {
+				t.Fatalf("expected %d requests, got %d", len(test.queries), len(*requests))
+			}
+			for i, req := range *requests {
+				if req.URL.Path != "/v6/channels/1/messages" || req.URL.RawQuery != test.queries[i] {
+					t.Errorf("unexpected request: %s", req.URL)
+				}
+			}
+			if len(messages) != test.total || messages[0].ID != test.first || messages[len(messages)-1].ID != test.last {
+				t.Errorf("unexpected messages: %d from %s to %s", len(messages), messages[0].ID, messages[len(messages)-1].ID)
+			}
+		})
+	}
+
+	t.Run("cancelled", func(t *testing.T) {
+		c, requests := newTestAPI(t, serveMessages(250))
+		ctx, cancel := context.WithCancel(context.Background())
+
+		it := c.MessagesBeforeContext(ctx, "1", "", 0)
+		if !it.Next() {
+			t.Fatal(it.Err())
+		}
+		cancel()
+		if it.Next() || !errors.Is(it.Err(), context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", it.Err())
+		}
+		if it.Next() || len(*requests) != 1 {
+			t.Errorf("the iteration went on after failing, %d requests", len(*requests))
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		c, _ := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"code": 50001, "message": "Missing Access"}`))
+		})
+
+		it := c.MessagesBefore("1", "", 0)
+		if it.Next() {
+			t.Fatal("expected the iteration to fail")
+		}
+		if _, ok := it.Err().(*rest.APIError); !ok {
+			t.Errorf("expected an *rest.APIError, got %v", it.Err())
+		}
+	})
+}
+
+func TestMemberIterator(t *testing.T) {
+	const count = 2500
+	c, requests := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		after, _ := strconv.Atoi(r.URL.Query().Get("after"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		var members []Member
+		for id := after + 1; id <= count && len(members) < limit; id++ {
+			members = append(members, Member{User: &User{ID: strconv.Itoa(id)}})
+		}
+		json.NewEncoder(w).Encode(members)
+	})
+
+	total := 0
+	it := c.MembersAfter("1", "", 0)
+	for it.Next() {
+		total += len(it.Batch())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	queries := []string{"limit=1000", "after=1000&limit=1000", "after=2000&limit=1000"}
+	if total != count || len(*requests) != len(queries) {
+		t.Fatalf("expected %d members in %d requests, got %d in %d", count, len(queries), total, len(*requests))
+	}
+	for i, req := range *requests {
+		if req.URL.Path != "/v6/guilds/1/members" || req.URL.RawQuery != queries[i] {
+			t.Errorf("unexpected request: %s", req.URL)
+		}
+	}
+}
+
+func TestBanIterator(t *testing.T) {
+	// bans aren't paginated by v6, every ban is returned whatever the cursor and the limit
+	for _, count := range []int{1200, maxBansPerRequest} {
+		c, requests := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+			var bans []Ban
+			for id := 1; id <= count; id++ {
+				bans = append(bans, Ban{User: &User{ID: strconv.Itoa(id)}})
+			}
+			json.NewEncoder(w).Encode(bans)
+		})
+
+		total := 0
+		it := c.BansAfter("1", "", 0)
+		for it.Next() {
+			total += len(it.Batch())
+		}
+		if it.Err() != nil || total != count || len(*requests) > 2 {
+			t.Errorf("expected %d bans, got %d in %d requests: %v", count, total, len(*requests), it.Err())
+		}
+	}
+}
+
+func TestUserIterator(t *testing.T) {
+	c, requests := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id": "5"}, {"id": "12"}]`))
+	})
+
+	it := c.ReactionsAfter("1", "2", "%F0%9F%91%8D", "3", 0)
+	if !it.Next() || len(it.Batch()) != 2 || it.Next() {
+		t.Fatalf("expected a batch of 2 users: %v", it.Err())
+	}
+
+	req := (*requests)[0]
+	if req.URL.EscapedPath() != "/v6/channels/1/messages/2/reactions/%F0%9F%91%8D" || req.URL.RawQuery != "after=3&limit=100" {
+		t.Errorf("unexpected request: %s", req.URL)
+	}
+}
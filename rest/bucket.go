@@ -1,48 +1,48 @@
 package rest
 
 import (
-	"bytes"
-	"fmt"
+	"context"
 	"io"
-	"mime/multipart"
 	"net/http"
-	"net/textproto"
 	"strconv"
-	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 )
 
 const (
+	// API_URL is the URL requests were sent to before the base URL and version could be configured
+	//
+	// Deprecated: use WithBaseURL and WithAPIVersion
 	API_URL = "https://discordapp.com/api/v6/"
 )
 
-var (
-	quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
-)
-
 type Bucket struct {
-	sync.Mutex
-	Manager   *RestManager
-	Route     string
-	Remaining int64
-	Limit     int64
-
-	resetTime  time.Time
-	httpClient *http.Client
+	sync.Mutex // guards Remaining and Limit
+	Manager    *RestManager
+	Route      string
+	Remaining  int64 // the remaining requests, as last seen by this process
+	Limit      int64 // the requests allowed until every reset, as last seen by this process
+
+	queueMu  sync.Mutex
+	busy     bool            // whether a request is being sent
+	waiters  []chan struct{} // the requests waiting for their turn, in submission order
+	lastUsed time.Time       // when the last request was given its turn
 }
 
+// File is a file uploaded with a request. Files are streamed, and sent again when the request is retried
+// only if their reader is an io.Seeker
 type File struct {
 	Name        string
 	Reader      io.Reader
 	ContentType string
+	Description string // the description of the attachment, its alt text
+	Spoiler     bool   // whether the attachment is hidden behind a spoiler
 }
 
 type ratelimitedResponse struct {
-	Message    string `json:"message"`
-	RetryAfter int    `json:"retry_after"`
-	Global     bool   `json:"global"`
+	Message    string  `json:"message"`
+	RetryAfter float64 `json:"retry_after"` // in milliseconds, seconds since v8
+	Global     bool    `json:"global"`
 }
 
 func NewBucket(r *RestManager, route string) *Bucket {
@@ -50,11 +50,9 @@ func NewBucket(r *RestManager, route string) *Bucket {
 		Mutex:     sync.Mutex{},
 		Manager:   r,
 		Route:     route,
-		Remaining: 1,
-		Limit:     1,
-
-		resetTime:  time.Time{},
-		httpClient: &http.Client{},
+		Remaining: newBucketState.Remaining,
+		Limit:     newBucketState.Limit,
+		lastUsed:  time.Now(),
 	}
 
 	return bucket
@@ -62,130 +60,366 @@ func NewBucket(r *RestManager, route string) *Bucket {
 
 // Request creates an http request
 func (b *Bucket) Request(method string, path string, body []byte, files ...File) (*http.Response, error) {
-	if b.Manager.GloballyRateLimited() {
-		<-time.After(time.Until(time.Unix(0, atomic.LoadInt64(b.Manager.global))))
-	}
+	return b.RequestContext(context.Background(), method, path, body, files...)
+}
 
-	if b.Remaining < 1 {
-		<-time.After(time.Until(b.resetTime))
+// RequestContext creates an http request, aborting rate limit waits and the request itself once the
+// context is done
+func (b *Bucket) RequestContext(ctx context.Context, method string, path string, body []byte, files ...File) (*http.Response, error) {
+	payload, err := newPayload(body, files)
+	if err != nil {
+		return nil, err
 	}
 
-	b.Lock()
-	defer b.Unlock()
-
-	var req *http.Request
+	header := http.Header{"Content-Type": {payload.contentType}}
+	return b.request(ctx, method, b.Manager.URL(path), ParseRoute(method, path), header, payload, false)
+}
 
-	if len(files) > 0 {
-		buf := &bytes.Buffer{}
-		bodywriter := multipart.NewWriter(buf)
+// request sends an already encoded body to the URL of a route once the bucket, and the manager, aren't
+// rate limited, retrying it following the retry policy. Requests are sent one at a time in submission
+// order, so they reach Discord in the same order: a request keeps its turn while it's retried, the
+// requests queued behind it waiting until it succeeds or gives up. The header is added to the request,
+// overriding the default ones
+func (b *Bucket) request(ctx context.Context, method, url, route string, header http.Header, payload *payload, retryServerErrors bool) (*http.Response, error) {
+	if err := b.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer b.release()
 
-		var p io.Writer
+	retry := b.Manager.Retry
+	for attempt := 1; ; attempt++ {
+		// errors while updating the rate limit headers don't fail the request
+		resp, err := b.send(ctx, method, url, route, header, payload)
+		if resp == nil {
+			return nil, err
+		}
+		if !retryable(resp.StatusCode, method, retryServerErrors) || attempt >= retry.MaxAttempts || !payload.replayable {
+			if resp.StatusCode == http.StatusTooManyRequests {
+				b.Manager.rateLimited(b, resp, method, url, route, attempt)
+			}
 
-		h := make(textproto.MIMEHeader)
-		h.Set("Content-Disposition", `form-data; name="payload_json"`)
-		h.Set("Content-Type", "application/json")
+			return resp, nil
+		}
 
-		p, err := bodywriter.CreatePart(h)
-		if err != nil {
-			panic(err)
+		delay := retry.backoff(attempt)
+		if resp.StatusCode == http.StatusTooManyRequests {
+			// the next attempt waits for the rate limit
+			delay = 0
+			b.Manager.rateLimited(b, resp, method, url, route, attempt)
 		}
+		resp.Body.Close()
 
-		if _, err = p.Write(body); err != nil {
-			panic(err)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
 		}
+	}
+}
 
-		for i, file := range files {
-			h := make(textproto.MIMEHeader)
-			h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file%d"; filename="%s"`, i, quoteEscaper.Replace(file.Name)))
-			contentType := file.ContentType
-			if contentType == "" {
-				contentType = "application/octet-stream"
-			}
-			h.Set("Content-Type", contentType)
+// send sends a single attempt of a request once neither the bucket nor the manager are rate limited,
+// updating the bucket from the response
+func (b *Bucket) send(ctx context.Context, method, url, route string, header http.Header, payload *payload) (*http.Response, error) {
+	if err := b.wait(ctx, route); err != nil {
+		return nil, err
+	}
 
-			p, err = bodywriter.CreatePart(h)
-			if err != nil {
-				panic(err)
-			}
+	body, err := payload.getBody()
+	if err != nil {
+		return nil, err
+	}
 
-			if _, err = io.Copy(p, file.Reader); err != nil {
-				fmt.Printf("%#v", file.Reader)
-				panic(err)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	req.ContentLength = payload.length
+	if payload.replayable {
+		req.GetBody = payload.getBody
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if b.Manager.Token != "" {
+		// requests authenticated otherwise, such as webhooks executed with their token, are sent without one
+		req.Header.Set("Authorization", "Bot "+b.Manager.Token)
+	}
+	req.Header.Set("User-Agent", b.Manager.userAgent)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := b.Manager.client.Do(req)
+	if err != nil {
+		// no response tells the reset of the window
+		_ = b.update(route, endPending)
+		return nil, err
+	}
+
+	err = b.UpdateHeaders(resp, route)
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// acquire waits for the turn of a request in the queue of the bucket
+func (b *Bucket) acquire(ctx context.Context) error {
+	b.queueMu.Lock()
+	b.lastUsed = time.Now()
+	if !b.busy {
+		b.busy = true
+		b.queueMu.Unlock()
+		return nil
+	}
+
+	turn := make(chan struct{})
+	b.waiters = append(b.waiters, turn)
+	b.queueMu.Unlock()
+
+	select {
+	case <-turn:
+		return nil
+	case <-ctx.Done():
+		b.queueMu.Lock()
+		defer b.queueMu.Unlock()
+
+		for i, waiter := range b.waiters {
+			if waiter == turn {
+				b.waiters = append(b.waiters[:i], b.waiters[i+1:]...)
+				return ctx.Err()
 			}
 		}
 
-		err = bodywriter.Close()
-		if err != nil {
-			panic(err)
-		}
+		// the turn was given while the context was done, pass it on
+		b.next()
+		return ctx.Err()
+	}
+}
 
-		req, err = http.NewRequest(method, API_URL+path, bytes.NewBuffer(buf.Bytes()))
-		if err != nil {
-			panic(err)
-		}
+// idle returns whether no request used the bucket for bucketIdleTimeout
+func (b *Bucket) idle() bool {
+	b.queueMu.Lock()
+	defer b.queueMu.Unlock()
 
-		req.Header.Set("Content-Type", bodywriter.FormDataContentType())
-	} else {
-		var err error
-		req, err = http.NewRequest(method, API_URL+path, bytes.NewBuffer(body))
-		if err != nil {
-			return nil, err
-		}
+	return !b.busy && len(b.waiters) == 0 && time.Since(b.lastUsed) > bucketIdleTimeout
+}
+
+// release gives the turn to the next request in the queue
+func (b *Bucket) release() {
+	b.queueMu.Lock()
+	defer b.queueMu.Unlock()
 
-		req.Header.Set("Content-Type", "application/json")
+	b.next()
+}
+
+// next gives the turn to the first waiting request, queueMu must be held
+func (b *Bucket) next() {
+	if len(b.waiters) == 0 {
+		b.busy = false
+		return
 	}
 
-	req.Header.Set("Authorization", "Bot "+b.Manager.Token)
-	req.Header.Set("User-Agent", "DiscordBot (https://github.com/Soumil07/gocord, v1)")
+	turn := b.waiters[0]
+	b.waiters = b.waiters[1:]
+	close(turn)
+}
+
+// pendingReset is the reset assumed for a window until the response of its first request tells the real one
+const pendingReset = 5 * time.Second
 
-	resp, err := b.httpClient.Do(req)
+// wait parks the request at the head of the queue until neither the manager nor the bucket are rate
+// limited, then takes one of the remaining requests of the route
+func (b *Bucket) wait(ctx context.Context, route string) error {
+	global, err := b.Manager.store.Global()
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if err := sleep(ctx, time.Until(global)); err != nil {
+		return err
+	}
+
+	for {
+		var resetTime time.Time
+		err := b.update(route, func(state *BucketState) {
+			if state.Remaining < 1 {
+				if time.Now().Before(state.ResetTime) {
+					resetTime = state.ResetTime
+					return
+				}
+				// the bucket was reset, a single request discovers the limit while it's unknown
+				state.Remaining = state.Limit
+				if state.Remaining < 1 {
+					state.Remaining = 1
+				}
+			}
+			state.Remaining--
+
+			if !time.Now().Before(state.ResetTime) {
+				// a new window starts, its reset is unknown until the response arrives
+				state.ResetTime = time.Now().Add(pendingReset)
+				state.Pending = true
+			}
+		})
+		if err != nil || resetTime.IsZero() {
+			return err
+		}
+
+		if err := sleep(ctx, time.Until(resetTime)); err != nil {
+			return err
+		}
 	}
+}
 
-	err = b.UpdateHeaders(resp, path)
+// update updates the state of the bucket of a route in the store, keeping a copy of it
+func (b *Bucket) update(route string, update func(*BucketState)) error {
+	var updated BucketState
+	err := b.Manager.store.UpdateBucket(b.Manager.stateKey(route), func(state *BucketState) {
+		update(state)
+		updated = *state
+	})
 	if err != nil {
-		return resp, err
+		return err
 	}
 
-	return resp, nil
+	b.Lock()
+	b.Remaining = updated.Remaining
+	b.Limit = updated.Limit
+	b.Unlock()
+
+	return nil
 }
 
-func (b *Bucket) UpdateHeaders(resp *http.Response, path string) error {
-	remaining := resp.Header.Get("X-Ratelimit-Remaining")
-	reset := resp.Header.Get("X-Ratelimit-Reset")
-	global := resp.Header.Get("X-RateLimit-Global")
-	retryAfter := resp.Header.Get("Retry-After")
+// delay rate limits the bucket of a route, or the whole manager, for at least the duration
+func (b *Bucket) delay(route string, d time.Duration, global bool) error {
+	resetTime := time.Now().Add(d)
+	if global {
+		return b.Manager.store.SetGlobal(resetTime)
+	}
 
-	if retryAfter != "" {
-		parsed, _ := strconv.ParseInt(retryAfter, 10, 64)
-		resetTime := time.Now().Add(time.Duration(parsed) * time.Millisecond)
-		if global != "" {
-			atomic.StoreInt64(b.Manager.global, resetTime.UnixNano())
-		} else {
-			b.resetTime = resetTime
+	return b.update(route, func(state *BucketState) {
+		state.Remaining = 0
+		if state.Pending || resetTime.After(state.ResetTime) {
+			state.ResetTime = resetTime
+			state.Pending = false
 		}
-	} else if reset != "" {
-		dTime, err := http.ParseTime(resp.Header.Get("Date"))
-		if err != nil {
+	})
+}
+
+// sleep waits for the duration, returning the context's error if it's done first
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// the scopes of a rate limit, reported in the X-RateLimit-Scope header of 429 responses
+const (
+	scopeUser   = "user"   // the bot's own limit on the route
+	scopeGlobal = "global" // the bot's global limit, pausing every bucket
+	scopeShared = "shared" // the limit of the resource, shared with other users
+)
+
+// UpdateHeaders updates the bucket from the rate limit headers of a response to the route
+func (b *Bucket) UpdateHeaders(resp *http.Response, route string) error {
+	remaining := resp.Header.Get("X-RateLimit-Remaining")
+	limit := resp.Header.Get("X-RateLimit-Limit")
+	resetAfter := resp.Header.Get("X-RateLimit-Reset-After")
+	hash := resp.Header.Get("X-RateLimit-Bucket")
+	scope := resp.Header.Get("X-RateLimit-Scope")
+	global := resp.Header.Get("X-RateLimit-Global") != "" || scope == scopeGlobal
+
+	if hash != "" && !global {
+		if err := b.Manager.setBucketHash(route, hash, b); err != nil {
 			return err
 		}
+	}
 
-		unixTime, err := strconv.ParseInt(reset, 10, 64)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter, err := parseSeconds(resp.Header.Get("Retry-After"))
 		if err != nil {
 			return err
 		}
 
-		b.resetTime = time.Now().Add(time.Unix(unixTime, 0).Sub(dTime) + time.Millisecond*250)
+		switch {
+		case global:
+			if err := b.delay(route, retryAfter, true); err != nil {
+				return err
+			}
+			// the bucket headers aren't sent with the global limit
+			return b.update(route, endPending)
+		case scope == scopeShared:
+			// the remaining requests don't reflect the bot's own usage of the route
+			return b.delay(route, retryAfter, false)
+		}
 	}
 
+	var state BucketState
+	var err error
+	if resetAfter != "" {
+		var parsed time.Duration
+		if parsed, err = parseSeconds(resetAfter); err != nil {
+			return err
+		}
+		state.ResetTime = time.Now().Add(parsed)
+	}
+	if limit != "" {
+		if state.Limit, err = strconv.ParseInt(limit, 10, 32); err != nil {
+			return err
+		}
+	}
 	if remaining != "" {
-		parsedRemaining, err := strconv.ParseInt(remaining, 10, 32)
-		if err != nil {
+		if state.Remaining, err = strconv.ParseInt(remaining, 10, 32); err != nil {
 			return err
 		}
-		b.Remaining = parsedRemaining
 	}
 
-	return nil
+	return b.update(route, func(current *BucketState) {
+		// requests sent meanwhile by other processes sharing the store may not be counted by the response,
+		// unless nothing was known about the window
+		trusted := current.Limit == 0 || !time.Now().Before(current.ResetTime)
+		if remaining != "" && (trusted || state.Remaining < current.Remaining) {
+			current.Remaining = state.Remaining
+		}
+		if limit != "" {
+			current.Limit = state.Limit
+		}
+		if resetAfter != "" {
+			current.ResetTime = state.ResetTime
+			current.Pending = false
+		} else {
+			endPending(current)
+		}
+	})
+}
+
+// endPending ends the window of a bucket waiting for its reset, when the response doesn't tell it
+func endPending(state *BucketState) {
+	if state.Pending {
+		state.ResetTime = time.Now()
+		state.Pending = false
+	}
+}
+
+// parseSeconds parses a header holding a decimal number of seconds
+func parseSeconds(header string) (time.Duration, error) {
+	if header == "" {
+		return 0, nil
+	}
+
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
 }
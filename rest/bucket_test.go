@@ -0,0 +1,155 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRequestContext(t *testing.T) {
+	t.Run("cancelled while rate limited", func(t *testing.T) {
+		route := ParseRoute(http.MethodGet, "/channels/1/messages")
+		bucket := NewBucket(NewRestManager("token"), route)
+		if err := bucket.delay(route, time.Minute, false); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := bucket.RequestContext(ctx, http.MethodGet, "/channels/1/messages", nil)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("the rate limit wait wasn't aborted, waited %s", elapsed)
+		}
+	})
+
+	t.Run("already cancelled", func(t *testing.T) {
+		route := ParseRoute(http.MethodGet, "/channels/1/messages")
+		bucket := NewBucket(NewRestManager("token"), route)
+		err := bucket.update(route, func(state *BucketState) {
+			state.Remaining = 0
+			state.ResetTime = time.Now().Add(-time.Second)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err = bucket.RequestContext(ctx, http.MethodGet, "/channels/1/messages", nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestQueue(t *testing.T) {
+	const limit, requests = 5, 12
+	window := 200 * time.Millisecond
+
+	// the server allows 5 requests every 200ms, answering with 429 once they're exhausted
+	var mu sync.Mutex
+	var received []string
+	var reset time.Time
+	var remaining int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if now := time.Now(); now.After(reset) {
+			reset = now.Add(window)
+			remaining = limit
+		}
+
+		h := w.Header()
+		h.Set("X-RateLimit-Bucket", "a")
+		h.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+		h.Set("X-RateLimit-Reset-After", strconv.FormatFloat(time.Until(reset).Seconds(), 'f', 3, 64))
+		if remaining == 0 {
+			h.Set("X-RateLimit-Remaining", "0")
+			h.Set("Retry-After", strconv.FormatFloat(time.Until(reset).Seconds(), 'f', 3, 64))
+			h.Set("X-RateLimit-Scope", scopeUser)
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		remaining--
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+
+		body, _ := ioutil.ReadAll(r.Body)
+		received = append(received, string(body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	manager := NewRestManager("token", WithBaseURL(server.URL))
+	manager.Retry.MaxAttempts = 1
+
+	var wg sync.WaitGroup
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- manager.Do(http.MethodPost, "/channels/1/messages", []byte(strconv.Itoa(i)), nil)
+		}(i)
+		// leaves time for the request to be queued, so the submission order is known
+		time.Sleep(5 * time.Millisecond)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, body := range received {
+		if body != strconv.Itoa(i) {
+			t.Fatalf("requests were reordered: %v", received)
+		}
+	}
+	if len(received) != requests {
+		t.Errorf("expected %d requests, got %d", requests, len(received))
+	}
+
+	t.Run("cancelled waiters leave the queue", func(t *testing.T) {
+		bucket := NewBucket(manager, "POST /channels/2/messages")
+		if err := bucket.acquire(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelled := make(chan error)
+		go func() { cancelled <- bucket.acquire(ctx) }()
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+		if err := <-cancelled; !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+
+		next := make(chan error)
+		go func() { next <- bucket.acquire(context.Background()) }()
+		time.Sleep(10 * time.Millisecond)
+		bucket.release()
+
+		select {
+		case err := <-next:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("the queue is stuck")
+		}
+	})
+}
@@ -4,6 +4,14 @@ import "fmt"
 
 // implements helper functions for REST API endpoints
 
+func Channel(channelID string) string {
+	return format("/channels/%s", channelID)
+}
+
+func ChannelPermission(channelID, overwriteID string) string {
+	return format("/channels/%s/permissions/%s", channelID, overwriteID)
+}
+
 func ChannelMessages(channelID string) string {
 	return format("/channels/%s/messages", channelID)
 }
@@ -20,6 +28,10 @@ func ChannelMessageReactionsAll(channelID, messageID string) string {
 	return format("/channels/%s/messages/%s/reactions", channelID, messageID)
 }
 
+func ChannelMessageReaction(channelID, messageID, emoji string) string {
+	return format("/channels/%s/messages/%s/reactions/%s", channelID, messageID, emoji)
+}
+
 func ChannelBulkDelete(channelID string) string {
 	return format("%s/bulk-delete", ChannelMessages(channelID))
 }
@@ -32,10 +44,62 @@ func UserGuild(ID, guildID string) string {
 	return format("/users/%s/guilds/%s", ID, guildID)
 }
 
+func GuildChannels(guildID string) string {
+	return format("/guilds/%s/channels", guildID)
+}
+
+func GuildRoles(guildID string) string {
+	return format("/guilds/%s/roles", guildID)
+}
+
+func GuildRole(guildID, roleID string) string {
+	return format("/guilds/%s/roles/%s", guildID, roleID)
+}
+
+func GuildMember(guildID, userID string) string {
+	return format("/guilds/%s/members/%s", guildID, userID)
+}
+
+func GuildMemberRole(guildID, userID, roleID string) string {
+	return format("/guilds/%s/members/%s/roles/%s", guildID, userID, roleID)
+}
+
+func GuildEmojis(guildID string) string {
+	return format("/guilds/%s/emojis", guildID)
+}
+
+func GuildMembers(guildID string) string {
+	return format("/guilds/%s/members", guildID)
+}
+
+func GuildBans(guildID string) string {
+	return format("/guilds/%s/bans", guildID)
+}
+
 func GuildBanMember(guildID, userID string) string {
 	return format("/guilds/%s/bans/%s", guildID, userID)
 }
 
+func ChannelWebhooks(channelID string) string {
+	return format("/channels/%s/webhooks", channelID)
+}
+
+func GuildWebhooks(guildID string) string {
+	return format("/guilds/%s/webhooks", guildID)
+}
+
+func Webhook(webhookID string) string {
+	return format("/webhooks/%s", webhookID)
+}
+
+func WebhookToken(webhookID, token string) string {
+	return format("/webhooks/%s/%s", webhookID, token)
+}
+
+func WebhookMessage(webhookID, token, messageID string) string {
+	return format("/webhooks/%s/%s/messages/%s", webhookID, token, messageID)
+}
+
 func Invite(code string) string {
 	return format("/invites/%s", code)
 }
@@ -0,0 +1,208 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// JSON error codes, as documented at https://discordapp.com/developers/docs/topics/opcodes-and-status-codes#json
+const (
+	ErrCodeUnknownAccount             = 10001
+	ErrCodeUnknownApplication         = 10002
+	ErrCodeUnknownChannel             = 10003
+	ErrCodeUnknownGuild               = 10004
+	ErrCodeUnknownIntegration         = 10005
+	ErrCodeUnknownInvite              = 10006
+	ErrCodeUnknownMember              = 10007
+	ErrCodeUnknownMessage             = 10008
+	ErrCodeUnknownOverwrite           = 10009
+	ErrCodeUnknownProvider            = 10010
+	ErrCodeUnknownRole                = 10011
+	ErrCodeUnknownToken               = 10012
+	ErrCodeUnknownUser                = 10013
+	ErrCodeUnknownEmoji               = 10014
+	ErrCodeUnknownWebhook             = 10015
+	ErrCodeUnknownBan                 = 10026
+	ErrCodeBotsCannotUseEndpoint      = 20001
+	ErrCodeOnlyBotsCanUseEndpoint     = 20002
+	ErrCodeMaxGuilds                  = 30001
+	ErrCodeMaxFriends                 = 30002
+	ErrCodeMaxPins                    = 30003
+	ErrCodeMaxRoles                   = 30005
+	ErrCodeMaxReactions               = 30010
+	ErrCodeMaxChannels                = 30013
+	ErrCodeUnauthorized               = 40001
+	ErrCodeMissingAccess              = 50001
+	ErrCodeInvalidAccountType         = 50002
+	ErrCodeCannotExecuteOnDM          = 50003
+	ErrCodeEmbedDisabled              = 50004
+	ErrCodeCannotEditOtherUserMessage = 50005
+	ErrCodeCannotSendEmptyMessage     = 50006
+	ErrCodeCannotSendMessagesToUser   = 50007
+	ErrCodeCannotSendMessagesInVoice  = 50008
+	ErrCodeVerificationLevelTooHigh   = 50009
+	ErrCodeOAuth2ApplicationNoBot     = 50010
+	ErrCodeOAuth2ApplicationLimit     = 50011
+	ErrCodeInvalidOAuthState          = 50012
+	ErrCodeMissingPermissions         = 50013
+	ErrCodeInvalidAuthToken           = 50014
+	ErrCodeNoteTooLong                = 50015
+	ErrCodeInvalidBulkDeleteCount     = 50016
+	ErrCodeCannotPinInOtherChannel    = 50019
+	ErrCodeCannotExecuteOnSystemMsg   = 50021
+	ErrCodeMessageTooOldToBulkDelete  = 50034
+	ErrCodeInvalidFormBody            = 50035
+	ErrCodeInviteAcceptedToNoBotGuild = 50036
+	ErrCodeInvalidAPIVersion          = 50041
+	ErrCodeReactionBlocked            = 90001
+)
+
+// APIError is returned when Discord responds with an error status code
+type APIError struct {
+	StatusCode int             `json:"-"`       // the HTTP status code
+	Code       int             `json:"code"`    // the JSON error code, 0 when the body isn't a JSON error
+	Message    string          `json:"message"` // the error message
+	Errors     json.RawMessage `json:"errors"`  // the nested errors of each invalid field, use FieldErrors to read them
+}
+
+// FieldError is the error of an invalid field sent in the request body
+type FieldError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// newAPIError creates an error from an error response and its body
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	err := &APIError{StatusCode: resp.StatusCode}
+	if json.Unmarshal(body, err) != nil || err.Message == "" {
+		err.Message = strings.TrimSpace(string(body))
+		if err.Message == "" {
+			err.Message = http.StatusText(resp.StatusCode)
+		}
+	}
+
+	return err
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("HTTP %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Code != 0 {
+		msg = fmt.Sprintf("%s, code %d: %s", msg, e.Code, e.Message)
+	} else {
+		msg = fmt.Sprintf("%s: %s", msg, e.Message)
+	}
+
+	fields := e.FieldErrors()
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, fieldErr := range fields[key] {
+			msg = fmt.Sprintf("%s\n%s: %s", msg, key, fieldErr.Message)
+		}
+	}
+
+	return msg
+}
+
+// FieldErrors returns the errors of each invalid field, keyed by the dotted path of the field such as
+// "embed.fields.0.name"
+func (e *APIError) FieldErrors() map[string][]FieldError {
+	out := make(map[string][]FieldError)
+	if len(e.Errors) == 0 {
+		return out
+	}
+
+	var nested map[string]json.RawMessage
+	if json.Unmarshal(e.Errors, &nested) != nil {
+		return out
+	}
+	flattenErrors("", nested, out)
+
+	return out
+}
+
+func flattenErrors(prefix string, nested map[string]json.RawMessage, out map[string][]FieldError) {
+	for key, value := range nested {
+		if key == "_errors" {
+			var errs []FieldError
+			if json.Unmarshal(value, &errs) == nil {
+				out[prefix] = append(out[prefix], errs...)
+			}
+			continue
+		}
+
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		var child map[string]json.RawMessage
+		if json.Unmarshal(value, &child) == nil {
+			flattenErrors(path, child, out)
+		}
+	}
+}
+
+// HasCode checks if the error is an APIError with the supplied JSON error code
+func HasCode(err error, code int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// HasStatus checks if the error is an APIError with the supplied HTTP status code
+func HasStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// IsUnknownMessage checks if the error was caused by a message that doesn't exist
+func IsUnknownMessage(err error) bool {
+	return HasCode(err, ErrCodeUnknownMessage)
+}
+
+// IsUnknownChannel checks if the error was caused by a channel that doesn't exist
+func IsUnknownChannel(err error) bool {
+	return HasCode(err, ErrCodeUnknownChannel)
+}
+
+// IsUnknownGuild checks if the error was caused by a guild that doesn't exist
+func IsUnknownGuild(err error) bool {
+	return HasCode(err, ErrCodeUnknownGuild)
+}
+
+// IsUnknownMember checks if the error was caused by a member that isn't in the guild
+func IsUnknownMember(err error) bool {
+	return HasCode(err, ErrCodeUnknownMember)
+}
+
+// IsUnknownUser checks if the error was caused by a user that doesn't exist
+func IsUnknownUser(err error) bool {
+	return HasCode(err, ErrCodeUnknownUser)
+}
+
+// IsMissingAccess checks if the error was caused by the bot not having access to a resource
+func IsMissingAccess(err error) bool {
+	return HasCode(err, ErrCodeMissingAccess)
+}
+
+// IsMissingPermissions checks if the error was caused by the bot lacking permissions
+func IsMissingPermissions(err error) bool {
+	return HasCode(err, ErrCodeMissingPermissions)
+}
+
+// IsNotFound checks if the error is a 404 response
+func IsNotFound(err error) bool {
+	return HasStatus(err, http.StatusNotFound)
+}
+
+// IsForbidden checks if the error is a 403 response
+func IsForbidden(err error) bool {
+	return HasStatus(err, http.StatusForbidden)
+}
@@ -0,0 +1,36 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusBadRequest}
+	body := []byte(`{"code": 50035, "message": "Invalid Form Body", "errors": {"embed": {"fields": {"0": {"name": {"_errors": [{"code": "BASE_TYPE_REQUIRED", "message": "This field is required"}]}}}}}}`)
+
+	err := newAPIError(resp, body)
+	if err.StatusCode != http.StatusBadRequest || err.Code != ErrCodeInvalidFormBody || err.Message != "Invalid Form Body" {
+		t.Errorf("unexpected error: %#v", err)
+	}
+
+	fields := err.FieldErrors()
+	if len(fields["embed.fields.0.name"]) != 1 || fields["embed.fields.0.name"][0].Code != "BASE_TYPE_REQUIRED" {
+		t.Errorf("unexpected field errors: %#v", fields)
+	}
+
+	t.Run("helpers", func(t *testing.T) {
+		wrapped := fmt.Errorf("deleting message: %w", &APIError{StatusCode: http.StatusNotFound, Code: ErrCodeUnknownMessage})
+		if !IsUnknownMessage(wrapped) || !IsNotFound(wrapped) || IsMissingPermissions(wrapped) {
+			t.Fail()
+		}
+	})
+
+	t.Run("non JSON body", func(t *testing.T) {
+		err := newAPIError(&http.Response{StatusCode: http.StatusBadGateway}, []byte("<html>bad gateway</html>"))
+		if err.Code != 0 || err.Message != "<html>bad gateway</html>" {
+			t.Errorf("unexpected error: %#v", err)
+		}
+	})
+}
@@ -0,0 +1,17 @@
+package rest
+
+import (
+	"errors"
+	"os"
+)
+
+// errFileLock is returned by file stores on platforms without file locks
+var errFileLock = errors.New("file stores aren't supported on windows")
+
+func lockFile(file *os.File) error {
+	return errFileLock
+}
+
+func unlockFile(file *os.File) error {
+	return errFileLock
+}
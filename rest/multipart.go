@@ -0,0 +1,181 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+var (
+	quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+)
+
+// errBodyNotReplayable is returned when a request uploading files which can't be read again is retried
+var errBodyNotReplayable = errors.New("the files of the request can't be read again")
+
+// payload is the body of a request, which may be sent several times
+type payload struct {
+	contentType string
+	length      int64 // -1 when unknown
+	replayable  bool  // whether the body can be sent again
+	getBody     func() (io.ReadCloser, error)
+}
+
+// attachment is the metadata of a file, sent in the attachments array of the JSON payload
+type attachment struct {
+	ID          int    `json:"id"`
+	Filename    string `json:"filename"`
+	Description string `json:"description,omitempty"`
+}
+
+// newPayload returns the payload of a JSON body, along with its files as multipart form data. Files are
+// streamed while the request is sent rather than buffered
+func newPayload(body []byte, files []File) (*payload, error) {
+	if len(files) == 0 {
+		return &payload{
+			contentType: "application/json",
+			length:      int64(len(body)),
+			replayable:  true,
+			getBody: func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(bytes.NewReader(body)), nil
+			},
+		}, nil
+	}
+
+	body, err := withAttachments(body, files)
+	if err != nil {
+		return nil, err
+	}
+
+	// the offsets the files are read again from when the request is retried
+	offsets := make([]int64, len(files))
+	replayable := true
+	for i, file := range files {
+		seeker, ok := file.Reader.(io.Seeker)
+		if !ok {
+			replayable = false
+			break
+		}
+		if offsets[i], err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+
+	// every body shares the boundary of the content type
+	boundary := multipart.NewWriter(ioutil.Discard).Boundary()
+
+	var mu sync.Mutex
+	read := false
+	getBody := func() (io.ReadCloser, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if read {
+			if !replayable {
+				return nil, errBodyNotReplayable
+			}
+			for i, file := range files {
+				if _, err := file.Reader.(io.Seeker).Seek(offsets[i], io.SeekStart); err != nil {
+					return nil, err
+				}
+			}
+		}
+		read = true
+
+		reader, writer := io.Pipe()
+		bodywriter := multipart.NewWriter(writer)
+		bodywriter.SetBoundary(boundary)
+		go func() {
+			writer.CloseWithError(writeMultipart(bodywriter, body, files))
+		}()
+
+		return reader, nil
+	}
+
+	return &payload{
+		contentType: "multipart/form-data; boundary=" + boundary,
+		length:      -1,
+		replayable:  replayable,
+		getBody:     getBody,
+	}, nil
+}
+
+// withAttachments adds the metadata of the files to a JSON object, unless it already has some
+func withAttachments(body []byte, files []File) ([]byte, error) {
+	fields := make(map[string]json.RawMessage)
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &fields); err != nil {
+			// the payload isn't an object, it's sent as is
+			return body, nil
+		}
+	}
+	if _, ok := fields["attachments"]; ok {
+		return body, nil
+	}
+
+	attachments := make([]attachment, len(files))
+	for i, file := range files {
+		attachments[i] = attachment{ID: i, Filename: file.filename(), Description: file.Description}
+	}
+
+	encoded, err := json.Marshal(attachments)
+	if err != nil {
+		return nil, err
+	}
+	fields["attachments"] = encoded
+
+	return json.Marshal(fields)
+}
+
+// writeMultipart writes the JSON payload and the files as multipart form data
+func writeMultipart(bodywriter *multipart.Writer, body []byte, files []File) error {
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="payload_json"`)
+	h.Set("Content-Type", "application/json")
+
+	p, err := bodywriter.CreatePart(h)
+	if err != nil {
+		return err
+	}
+
+	if _, err = p.Write(body); err != nil {
+		return err
+	}
+
+	for i, file := range files {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="files[%d]"; filename="%s"`, i, quoteEscaper.Replace(file.filename())))
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		h.Set("Content-Type", contentType)
+
+		p, err = bodywriter.CreatePart(h)
+		if err != nil {
+			return err
+		}
+
+		if _, err = io.Copy(p, file.Reader); err != nil {
+			return fmt.Errorf("error while reading file %s: %w", file.Name, err)
+		}
+	}
+
+	return bodywriter.Close()
+}
+
+// filename returns the name the file is uploaded with, marking spoilers
+func (f File) filename() string {
+	if f.Spoiler {
+		return "SPOILER_" + f.Name
+	}
+
+	return f.Name
+}
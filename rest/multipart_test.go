@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// errReader fails once its content is read
+type errReader struct {
+	io.Reader
+}
+
+func (r errReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == io.EOF {
+		return n, errors.New("disk failure")
+	}
+	return n, err
+}
+
+func TestMultipart(t *testing.T) {
+	var requests []*http.Request
+	var payloads []string
+	var files [][]string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, r)
+		payloads = append(payloads, r.FormValue("payload_json"))
+
+		var names []string
+		for i := 0; ; i++ {
+			_, header, err := r.FormFile("files[" + strconv.Itoa(i) + "]")
+			if err != nil {
+				break
+			}
+			names = append(names, header.Filename)
+		}
+		files = append(files, names)
+
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	manager := NewRestManager("token", WithBaseURL(server.URL))
+	manager.Retry.Backoff = time.Millisecond
+
+	t.Run("attachments", func(t *testing.T) {
+		err := manager.Do(http.MethodPost, "/channels/1/messages", []byte(`{"content":"hi"}`), nil,
+			File{Name: "cat.png", Reader: strings.NewReader("cat"), Description: "a cat"},
+			File{Name: "plot.txt", Reader: strings.NewReader("twist"), Spoiler: true},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req := requests[len(requests)-1]
+		if req.ContentLength != -1 || len(req.TransferEncoding) == 0 || req.TransferEncoding[0] != "chunked" {
+			t.Errorf("the body wasn't streamed: length %d, transfer encoding %v", req.ContentLength, req.TransferEncoding)
+		}
+
+		var payload struct {
+			Content     string       `json:"content"`
+			Attachments []attachment `json:"attachments"`
+		}
+		if err := json.Unmarshal([]byte(payloads[len(payloads)-1]), &payload); err != nil {
+			t.Fatal(err)
+		}
+		expected := []attachment{{0, "cat.png", "a cat"}, {1, "SPOILER_plot.txt", ""}}
+		if payload.Content != "hi" || len(payload.Attachments) != 2 || payload.Attachments[0] != expected[0] || payload.Attachments[1] != expected[1] {
+			t.Errorf("unexpected payload: %#v", payload)
+		}
+
+		names := files[len(files)-1]
+		if len(names) != 2 || names[0] != "cat.png" || names[1] != "SPOILER_plot.txt" {
+			t.Errorf("unexpected files: %v", names)
+		}
+	})
+
+	t.Run("upload errors", func(t *testing.T) {
+		err := manager.Do(http.MethodPost, "/channels/1/messages", []byte(`{}`), nil,
+			File{Name: "broken.bin", Reader: errReader{strings.NewReader("data")}},
+		)
+		if err == nil || !strings.Contains(err.Error(), "disk failure") {
+			t.Errorf("expected the upload error, got %v", err)
+		}
+	})
+
+	t.Run("files which can't be read again aren't retried", func(t *testing.T) {
+		status = http.StatusServiceUnavailable
+		defer func() { status = http.StatusOK }()
+
+		sent := len(requests)
+		err := manager.Do(http.MethodPost, "/channels/1/messages", []byte(`{}`), nil,
+			File{Name: "stream.bin", Reader: ioutil.NopCloser(strings.NewReader("data"))},
+		)
+		if apiErr, ok := err.(*APIError); !ok || apiErr.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("expected a 503 error, got %v", err)
+		}
+		if len(requests)-sent != 1 {
+			t.Errorf("expected a single request, got %d", len(requests)-sent)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultBaseURL is the URL requests are sent to, without the API version
+	DefaultBaseURL = "https://discordapp.com/api"
+	// DefaultAPIVersion is the API version requests are sent to
+	DefaultAPIVersion = 6
+	// userAgent identifies the library, as required by Discord
+	userAgent = "DiscordBot (https://github.com/Soumil07/gocord, v1)"
+)
+
+// Option configures a RestManager
+type Option func(*RestManager)
+
+// WithBaseURL sends requests to another base URL, such as a proxy. The API version is appended to it
+func WithBaseURL(url string) Option {
+	return func(r *RestManager) {
+		r.baseURL = strings.TrimSuffix(url, "/")
+	}
+}
+
+// WithAPIVersion sends requests to another API version
+func WithAPIVersion(version int) Option {
+	return func(r *RestManager) {
+		r.version = version
+	}
+}
+
+// WithHTTPClient sends requests using the client
+func WithHTTPClient(client *http.Client) Option {
+	return func(r *RestManager) {
+		r.client = client
+	}
+}
+
+// WithTransport sends requests through the transport, keeping the other settings of the client
+func WithTransport(transport http.RoundTripper) Option {
+	return func(r *RestManager) {
+		client := *r.client
+		client.Transport = transport
+		r.client = &client
+	}
+}
+
+// WithUserAgent appends a suffix to the User-Agent requests are sent with
+func WithUserAgent(suffix string) Option {
+	return func(r *RestManager) {
+		r.userAgent = userAgent + " " + suffix
+	}
+}
+
+// WithStore keeps the rate limits in the store, which may be shared with other managers and processes
+// sending requests with the same token
+func WithStore(store RateLimitStore) Option {
+	return func(r *RestManager) {
+		r.store = store
+	}
+}
+
+// URL returns the URL a request to the path is sent to
+func (r *RestManager) URL(path string) string {
+	return fmt.Sprintf("%s/v%d%s", r.baseURL, r.version, path)
+}
@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// countingTransport counts the requests sent through it
+type countingTransport struct {
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestOptions(t *testing.T) {
+	var received *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	t.Run("URL", func(t *testing.T) {
+		manager := NewRestManager("token")
+		if url := manager.URL("/users/@me"); url != "https://discordapp.com/api/v6/users/@me" {
+			t.Errorf("unexpected default URL: %s", url)
+		}
+
+		manager = NewRestManager("token", WithBaseURL("http://proxy.local/api/"), WithAPIVersion(10))
+		if url := manager.URL("/users/@me"); url != "http://proxy.local/api/v10/users/@me" {
+			t.Errorf("unexpected URL: %s", url)
+		}
+	})
+
+	t.Run("requests", func(t *testing.T) {
+		transport := &countingTransport{}
+		manager := NewRestManager("token",
+			WithBaseURL(server.URL),
+			WithAPIVersion(9),
+			WithHTTPClient(&http.Client{}),
+			WithTransport(transport),
+			WithUserAgent("my-bot/1.0"),
+		)
+
+		if err := manager.Do(http.MethodGet, "/users/@me", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if received.URL.Path != "/v9/users/@me" {
+			t.Errorf("unexpected path: %s", received.URL.Path)
+		}
+		if ua := received.Header.Get("User-Agent"); ua != "DiscordBot (https://github.com/Soumil07/gocord, v1) my-bot/1.0" {
+			t.Errorf("unexpected User-Agent: %s", ua)
+		}
+		if received.Header.Get("Authorization") != "Bot token" {
+			t.Errorf("unexpected Authorization: %s", received.Header.Get("Authorization"))
+		}
+		if transport.requests != 1 {
+			t.Errorf("the request wasn't sent through the transport")
+		}
+	})
+}
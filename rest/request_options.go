@@ -0,0 +1,48 @@
+package rest
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// RequestOption configures a single request
+type RequestOption func(*requestOptions)
+
+// requestOptions are the settings of a single request
+type requestOptions struct {
+	header            http.Header
+	files             []File
+	retryServerErrors bool
+}
+
+// WithHeader sends the request with a header
+func WithHeader(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		o.header.Set(key, value)
+	}
+}
+
+// WithReason records the reason of a request in the audit log of the guild. An empty reason is ignored
+func WithReason(reason string) RequestOption {
+	return func(o *requestOptions) {
+		if reason != "" {
+			o.header.Set("X-Audit-Log-Reason", url.PathEscape(reason))
+		}
+	}
+}
+
+// WithFiles sends the files along with the request, as multipart form data
+func WithFiles(files ...File) RequestOption {
+	return func(o *requestOptions) {
+		o.files = append(o.files, files...)
+	}
+}
+
+// WithServerErrorRetries retries the request after a 502, 503 or 504 response even though its method isn't
+// idempotent. Only GET, HEAD, OPTIONS, PUT and DELETE requests are retried otherwise, since Discord may have
+// handled a request before failing, and a message created before a gateway timeout would be sent twice
+func WithServerErrorRetries() RequestOption {
+	return func(o *requestOptions) {
+		o.retryServerErrors = true
+	}
+}
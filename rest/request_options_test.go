@@ -0,0 +1,44 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestOptions(t *testing.T) {
+	var received *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	manager := NewRestManager("token", WithBaseURL(server.URL))
+
+	tests := []struct {
+		name    string
+		options []RequestOption
+		header  string
+		value   string
+	}{
+		{"reason", []RequestOption{WithReason("spam & raids/bots")}, "X-Audit-Log-Reason", "spam%20&%20raids%2Fbots"},
+		{"unicode reason", []RequestOption{WithReason("café ✓")}, "X-Audit-Log-Reason", "caf%C3%A9%20%E2%9C%93"},
+		{"empty reason", []RequestOption{WithReason("")}, "X-Audit-Log-Reason", ""},
+		{"header", []RequestOption{WithHeader("X-Custom", "value")}, "X-Custom", "value"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := manager.DoWithOptions(context.Background(), http.MethodDelete, "/guilds/1/bans/2", nil, nil, test.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if value := received.Header.Get(test.header); value != test.value {
+				t.Errorf("expected %s %q, got %q", test.header, test.value, value)
+			}
+		})
+	}
+}
@@ -6,99 +6,234 @@ package rest
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net/http"
 	"regexp"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 )
 
-var (
-	idRegex = regexp.MustCompile("[0-9]+")
-)
-
-func getBody(r io.Reader) []byte {
-	body, err := ioutil.ReadAll(r)
-	if err != nil {
-		panic(err)
-	}
-
-	return body
-}
-
 type RestManager struct {
 	Token string
+	Retry RetryPolicy // how requests failing with a rate limit or a server error are retried
+	// OnRateLimit is called whenever a request hits a rate limit, before it's sent again
+	OnRateLimit func(*RateLimit)
+
+	store   RateLimitStore
+	buckets *sync.Map // the request queues, keyed by route or by bucket hash and major parameter once known
+
+	sweepMu   sync.Mutex
+	lastSweep time.Time // when idle buckets were last dropped
 
-	global  *int64
-	buckets *sync.Map
+	baseURL   string
+	version   int
+	client    *http.Client
+	userAgent string
 }
 
-func NewRestManager(token string) *RestManager {
-	return &RestManager{
+// NewRestManager returns a rest manager sending requests with the token, configured by the options
+func NewRestManager(token string, options ...Option) *RestManager {
+	r := &RestManager{
 		Token:   token,
-		global:  new(int64),
+		Retry:   DefaultRetryPolicy,
+		store:   NewMemoryStore(),
 		buckets: &sync.Map{},
+
+		baseURL:   DefaultBaseURL,
+		version:   DefaultAPIVersion,
+		client:    &http.Client{},
+		userAgent: userAgent,
+	}
+
+	for _, option := range options {
+		option(r)
 	}
+
+	return r
 }
 
+// GloballyRateLimited returns whether every request is rate limited
 func (r *RestManager) GloballyRateLimited() bool {
-	globalTime := time.Unix(0, atomic.LoadInt64(r.global))
-	return time.Now().Before(globalTime)
+	global, err := r.store.Global()
+	return err == nil && time.Now().Before(global)
 }
 
+// GetBucket returns the bucket of a route returned by ParseRoute. Routes Discord reported the same bucket
+// hash for share a bucket, as long as their major parameter is the same
 func (r *RestManager) GetBucket(route string) *Bucket {
-	if bucket, ok := r.buckets.Load(route); ok {
+	r.sweepBuckets()
+
+	key := r.stateKey(route)
+	if bucket, ok := r.buckets.Load(key); ok {
 		return bucket.(*Bucket)
 	}
 
-	bucket := NewBucket(r, route)
-	r.buckets.Store(route, bucket)
+	bucket, _ := r.buckets.LoadOrStore(key, NewBucket(r, route))
+	return bucket.(*Bucket)
+}
+
+// bucketIdleTimeout is the time a bucket is kept after its last request. Buckets are kept per major
+// parameter, and would otherwise pile up for every channel and guild ever requested
+const bucketIdleTimeout = 10 * time.Minute
+
+// sweepBuckets drops the buckets left idle, at most once per bucketIdleTimeout. Their state is kept by the
+// store, so a dropped bucket is only a queue to create again
+func (r *RestManager) sweepBuckets() {
+	r.sweepMu.Lock()
+	if time.Since(r.lastSweep) < bucketIdleTimeout {
+		r.sweepMu.Unlock()
+		return
+	}
+	r.lastSweep = time.Now()
+	r.sweepMu.Unlock()
 
-	return bucket
+	r.buckets.Range(func(key, bucket interface{}) bool {
+		if bucket.(*Bucket).idle() {
+			r.buckets.Delete(key)
+		}
+		return true
+	})
 }
 
-func (r *RestManager) Do(method string, path string, body []byte, respBody interface{}, files ...File) error {
-	route := ParseRoute(method, path)
-	bucket := r.GetBucket(route)
+// stateKey returns the key of the bucket of a route, made of its bucket hash and major parameter once
+// the hash is known
+func (r *RestManager) stateKey(route string) string {
+	hash, err := r.store.BucketHash(routeTemplate(route))
+	if err != nil || hash == "" {
+		return route
+	}
+
+	return bucketKey(hash, route)
+}
 
-	resp, err := bucket.Request(method, path, body, files...)
+// setBucketHash records the bucket hash of a route, making the bucket the shared one for that hash
+// unless another route already provided it. Hashes are recorded per route template, as they don't depend
+// on the major parameter, and known ones are recorded again for the store to keep them while in use
+func (r *RestManager) setBucketHash(route, hash string, bucket *Bucket) error {
+	template := routeTemplate(route)
+	known, err := r.store.BucketHash(template)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer resp.Body.Close()
 
+	if known != hash {
+		r.buckets.LoadOrStore(bucketKey(hash, route), bucket)
+	}
+	return r.store.SetBucketHash(template, hash)
+}
+
+// bucketKey returns the key of the bucket identified by a hash for a route
+func bucketKey(hash, route string) string {
+	return hash + ":" + majorParameter(route)
+}
+
+// Do sends a request, decoding the response into respBody. An *APIError is returned when Discord responds
+// with an error status code
+func (r *RestManager) Do(method string, path string, body []byte, respBody interface{}, files ...File) error {
+	return r.DoContext(context.Background(), method, path, body, respBody, files...)
+}
+
+// DoContext is Do with a context, cancelling the request and any rate limit wait once the context is done.
+// Requests hitting a rate limit are sent again following the retry policy, and so are idempotent requests
+// failing with a server error. See WithServerErrorRetries for the other ones
+func (r *RestManager) DoContext(ctx context.Context, method string, path string, body []byte, respBody interface{}, files ...File) error {
+	return r.DoWithOptions(ctx, method, path, body, respBody, WithFiles(files...))
+}
+
+// DoWithOptions is DoContext with options applying to this request only, such as its headers
+func (r *RestManager) DoWithOptions(ctx context.Context, method string, path string, body []byte, respBody interface{}, options ...RequestOption) error {
+	opts := &requestOptions{header: http.Header{}}
+	for _, option := range options {
+		option(opts)
+	}
+
+	payload, err := newPayload(body, opts.files)
 	if err != nil {
-		// sometimes the error is while updating headers, so there is a body
-		if resp != nil {
-			body := getBody(resp.Body)
-			err := json.Unmarshal(body, respBody)
-			if err != nil {
-				return fmt.Errorf("error while unmarshalling response body: %s", err)
-			}
-
-			return err
-		}
+		return err
+	}
 
+	opts.header.Set("Content-Type", payload.contentType)
+	resp, err := r.send(ctx, method, r.URL(path), ParseRoute(method, path), opts.header, payload, opts.retryServerErrors)
+	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
-	body = getBody(resp.Body)
-	err = json.Unmarshal(body, respBody)
+	return decodeResponse(resp, respBody)
+}
+
+// Forward sends a request through the buckets of the manager, following the retry policy, and returns the
+// response with its body unread. The path is relative to the base URL and starts with the API version, so
+// requests made to any version can be forwarded. The header is added to the request, overriding the
+// default ones such as the Authorization
+func (r *RestManager) Forward(ctx context.Context, method, path string, header http.Header, body []byte) (*http.Response, error) {
+	route := ParseRoute(method, versionRegex.ReplaceAllString(path, ""))
+	payload, err := newPayload(body, nil)
 	if err != nil {
-		return fmt.Errorf("error while unmarshalling response body: %s", err)
+		return nil, err
 	}
 
-	return nil
+	return r.send(ctx, method, r.baseURL+path, route, header, payload, false)
+}
+
+// versionRegex matches the API version a path starts with
+var versionRegex = regexp.MustCompile(`^/v\d+`)
+
+// send sends a request to the URL of a route through its bucket until it succeeds, or the retry policy
+// gives up. Server errors of requests which aren't idempotent are only retried with retryServerErrors
+func (r *RestManager) send(ctx context.Context, method, url, route string, header http.Header, payload *payload, retryServerErrors bool) (*http.Response, error) {
+	return r.GetBucket(route).request(ctx, method, url, route, header, payload, retryServerErrors)
+}
+
+// rateLimited delays the bucket following a 429 response, and reports the rate limit. The body is left
+// readable for the response to be decoded
+func (r *RestManager) rateLimited(bucket *Bucket, resp *http.Response, method, url, route string, attempt int) {
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var limited ratelimitedResponse
+	_ = json.Unmarshal(body, &limited)
+
+	scope := resp.Header.Get("X-RateLimit-Scope")
+	global := limited.Global || resp.Header.Get("X-RateLimit-Global") != "" || scope == scopeGlobal
+
+	// the Retry-After header is already applied by the bucket, while the body is relied upon without it
+	retryAfter, err := parseSeconds(resp.Header.Get("Retry-After"))
+	if err != nil || retryAfter == 0 {
+		unit := time.Millisecond
+		if r.version >= 8 {
+			// retry_after is in seconds since v8
+			unit = time.Second
+		}
+		retryAfter = time.Duration(limited.RetryAfter * float64(unit))
+		_ = bucket.delay(route, retryAfter, global)
+	}
+
+	if r.OnRateLimit != nil {
+		r.OnRateLimit(&RateLimit{
+			Method:     method,
+			URL:        redactToken(url),
+			Bucket:     resp.Header.Get("X-RateLimit-Bucket"),
+			Scope:      scope,
+			Global:     global,
+			RetryAfter: retryAfter,
+			Attempt:    attempt,
+		})
+	}
 }
 
 // SimpleRequest creates a simple JSON request to the supplied URL
 func SimpleRequest(method string, url string, body []byte, respBody interface{}) error {
-	req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+	return SimpleRequestContext(context.Background(), method, url, body, respBody)
+}
+
+// SimpleRequestContext is SimpleRequest with a context
+func SimpleRequestContext(ctx context.Context, method string, url string, body []byte, respBody interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(body))
 	if err != nil {
 		return fmt.Errorf("error intializing request: %s", err.Error())
 	}
@@ -110,32 +245,105 @@ func SimpleRequest(method string, url string, body []byte, respBody interface{})
 
 	defer res.Body.Close()
 
-	err = json.Unmarshal(getBody(res.Body), respBody)
+	return decodeResponse(res, respBody)
+}
+
+// decodeResponse decodes the body of a response into respBody, or into an *APIError for error status codes
+func decodeResponse(resp *http.Response, respBody interface{}) error {
+	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("error unmarshalling json: %s", err.Error())
+		return fmt.Errorf("error while reading response body: %s", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newAPIError(resp, body)
+	}
+
+	if respBody == nil || len(body) == 0 {
+		return nil
+	}
+
+	err = json.Unmarshal(body, respBody)
+	if err != nil {
+		return fmt.Errorf("error while unmarshalling response body: %s", err)
 	}
 
 	return nil
 }
 
-// ParseRoute parses a route to be used in a bucket
-// adapted from the JavaScript version made by PoLLeN#5796
+// ParseRoute parses a route to be used in a bucket. The major parameter (the channel, guild or webhook
+// the route starts with) is kept, while every other ID is replaced since it doesn't change the rate limit.
+// Webhook tokens are replaced too, so they aren't kept by buckets and stores
 func ParseRoute(method string, route string) string {
 	url := strings.Split(route, "?")[0] // query strings don't count
-	ids := idRegex.FindAllString(url, -1)
-	// if one ID, return the url
-	if len(ids) == 1 {
-		return url
+	parts := strings.Split(url, "/")
+
+	for i := 1; i < len(parts); i++ {
+		part := parts[i]
+		switch {
+		case i == 2 && majorParameters[parts[1]]:
+			// the major parameter
+		case i == 3 && parts[1] == "webhooks":
+			// a webhook only has one token, the webhook ID is enough to tell its rate limits apart
+			parts[i] = ":token"
+		case i > 1 && parts[i-1] == "reactions":
+			// every emoji shares a bucket
+			parts[i] = ":emoji"
+		case isID(part):
+			parts[i] = ":id"
+		}
+	}
+
+	return method + " " + strings.Join(parts, "/")
+}
+
+// majorParameters are the resources rate limits are tracked separately for
+var majorParameters = map[string]bool{
+	"channels": true,
+	"guilds":   true,
+	"webhooks": true,
+}
+
+// majorParameter returns the major parameter of a route returned by ParseRoute, an empty string if none
+func majorParameter(route string) string {
+	parts := strings.Split(route, "/")
+	if len(parts) < 3 || !majorParameters[parts[1]] {
+		return ""
 	}
-	url = strings.Replace(url, ids[1], ":id", 1)
-	if method == http.MethodDelete && strings.Contains(url, "messages") {
-		// message deletes have their own ratelimits
-		return fmt.Sprintf("%s %s", http.MethodDelete, url)
+
+	return parts[2]
+}
+
+// routeTemplate returns a route returned by ParseRoute without its major parameter, the same for every
+// channel, guild or webhook
+func routeTemplate(route string) string {
+	parts := strings.SplitN(route, "/", 4)
+	if len(parts) < 3 || !majorParameters[parts[1]] {
+		return route
 	}
 
-	// adding reactions have their own ratelimit across the account
-	if strings.Contains(url, "/reactions/:id") {
-		return "/channels/messages/:id/reactions"
+	parts[2] = ":id"
+	return strings.Join(parts, "/")
+}
+
+// webhookTokenRegex matches the token of a webhook URL
+var webhookTokenRegex = regexp.MustCompile(`(/webhooks/[^/]+/)[^/?]+`)
+
+// redactToken replaces the webhook token of a URL, for it to be reported
+func redactToken(url string) string {
+	return webhookTokenRegex.ReplaceAllString(url, "${1}:token")
+}
+
+// isID returns whether a path segment is a snowflake
+func isID(part string) bool {
+	if part == "" {
+		return false
 	}
-	return url
+	for _, c := range part {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
 }
@@ -1,14 +1,23 @@
 package rest
 
 import (
+	"context"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestParseRoute(t *testing.T) {
 	t.Run("normal routes", func(t *testing.T) {
 		sample := "/channels/372539957824323584/messages/532935925194555392"
-		expected := "/channels/372539957824323584/messages/:id"
+		expected := "GET /channels/372539957824323584/messages/:id"
 		if ParseRoute(http.MethodGet, sample) != expected {
 			t.Errorf("Test failed, expected: %s", expected)
 		}
@@ -16,7 +25,7 @@ func TestParseRoute(t *testing.T) {
 
 	t.Run("individual routes", func(t *testing.T) {
 		sample := "/channels/372539957824323584"
-		if ParseRoute(http.MethodPut, sample) != sample {
+		if ParseRoute(http.MethodPut, sample) != "PUT "+sample {
 			t.Errorf("Test failed, expected: %s", sample)
 		}
 	})
@@ -28,4 +37,359 @@ func TestParseRoute(t *testing.T) {
 			t.Errorf("Test failed, expected: %s", expected)
 		}
 	})
+
+	tests := []struct {
+		name, method, path, route, major, template string
+	}{
+		{"guild routes", http.MethodPut, "/guilds/1/bans/2", "PUT /guilds/1/bans/:id", "1", "PUT /guilds/:id/bans/:id"},
+		{"webhook tokens", http.MethodPost, "/webhooks/1/token?wait=true", "POST /webhooks/1/:token", "1", "POST /webhooks/:id/:token"},
+		{"reactions", http.MethodPut, "/channels/1/messages/2/reactions/%F0%9F%91%8D/@me", "PUT /channels/1/messages/:id/reactions/:emoji/@me", "1", "PUT /channels/:id/messages/:id/reactions/:emoji/@me"},
+		{"no major parameter", http.MethodDelete, "/users/@me/guilds/1", "DELETE /users/@me/guilds/:id", "", "DELETE /users/@me/guilds/:id"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			route := ParseRoute(test.method, test.path)
+			if route != test.route {
+				t.Errorf("expected route %q, got %q", test.route, route)
+			}
+			if major := majorParameter(route); major != test.major {
+				t.Errorf("expected major parameter %q, got %q", test.major, major)
+			}
+			if template := routeTemplate(route); template != test.template {
+				t.Errorf("expected template %q, got %q", test.template, template)
+			}
+		})
+	}
+
+	t.Run("redacted tokens", func(t *testing.T) {
+		url := redactToken("https://discord.com/api/v6/webhooks/1/secret/messages/2?wait=true")
+		if url != "https://discord.com/api/v6/webhooks/1/:token/messages/2?wait=true" {
+			t.Errorf("the token was not redacted: %s", url)
+		}
+	})
+}
+
+// rateLimitResponse is a response of the test server, written with Discord's rate limit headers
+type rateLimitResponse struct {
+	status     int
+	bucket     string
+	remaining  int
+	resetAfter float64
+	scope      string
+	bodyOnly   bool // whether the retry_after of a 429 is only sent in the body
+}
+
+// newTestServer returns a manager sending requests to a server answering with the responses in order
+func newTestServer(t *testing.T, responses ...rateLimitResponse) (*RestManager, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(atomic.AddInt32(&requests, 1)) - 1
+		if i >= len(responses) {
+			i = len(responses) - 1
+		}
+		res := responses[i]
+
+		h := w.Header()
+		h.Set("X-RateLimit-Limit", "5")
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(res.remaining))
+		h.Set("X-RateLimit-Reset-After", strconv.FormatFloat(res.resetAfter, 'f', 3, 64))
+		// a skewed Date header must not be used
+		h.Set("Date", time.Now().Add(time.Hour).Format(http.TimeFormat))
+		if res.bucket != "" {
+			h.Set("X-RateLimit-Bucket", res.bucket)
+		}
+		switch res.status {
+		case http.StatusTooManyRequests:
+			if !res.bodyOnly {
+				h.Set("Retry-After", strconv.FormatFloat(res.resetAfter, 'f', 3, 64))
+			}
+			h.Set("X-RateLimit-Scope", res.scope)
+			if res.scope == scopeGlobal {
+				h.Set("X-RateLimit-Global", "true")
+			}
+			w.WriteHeader(res.status)
+			fmt.Fprintf(w, `{"message": "You are being rate limited.", "retry_after": %f, "global": %t}`, res.resetAfter*1000, res.scope == scopeGlobal)
+		case 0:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(res.status)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	manager := NewRestManager("token", WithBaseURL(server.URL))
+	manager.Retry = RetryPolicy{MaxAttempts: 5, Backoff: 50 * time.Millisecond, MaxBackoff: time.Second}
+	return manager, &requests
+}
+
+func TestRateLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []rateLimitResponse
+		method    string   // the method of the requests, POST when empty
+		paths     []string // requested in order
+		requests  int      // the requests received, one per path when 0
+		wait      time.Duration
+		limits    int  // the rate limits reported
+		global    bool // whether the rate limits reported are global
+	}{
+		{
+			name:      "remaining requests",
+			responses: []rateLimitResponse{{bucket: "a", remaining: 4, resetAfter: 1}},
+			paths:     []string{"/channels/1/messages", "/channels/1/messages"},
+		},
+		{
+			name:      "waits for the reset",
+			responses: []rateLimitResponse{{bucket: "a", remaining: 0, resetAfter: 0.2}},
+			paths:     []string{"/channels/1/messages", "/channels/1/messages"},
+			wait:      200 * time.Millisecond,
+		},
+		{
+			// the hash of a route is only known after its first request
+			name:      "routes sharing a bucket",
+			responses: []rateLimitResponse{{bucket: "a", remaining: 0, resetAfter: 0.2}},
+			paths:     []string{"/channels/1/messages", "/channels/1/messages/2/crosspost", "/channels/1/messages", "/channels/1/messages/2/crosspost"},
+			wait:      400 * time.Millisecond,
+		},
+		{
+			name:      "different major parameters",
+			responses: []rateLimitResponse{{bucket: "a", remaining: 0, resetAfter: 0.5}},
+			paths:     []string{"/channels/1/messages", "/channels/2/messages"},
+		},
+		{
+			name: "shared scope",
+			responses: []rateLimitResponse{
+				{status: http.StatusTooManyRequests, bucket: "a", remaining: 3, resetAfter: 0.2, scope: scopeShared},
+				{bucket: "a", remaining: 4, resetAfter: 1},
+			},
+			paths:    []string{"/channels/1/messages"},
+			requests: 2,
+			wait:     200 * time.Millisecond,
+			limits:   1,
+		},
+		{
+			name: "global scope",
+			responses: []rateLimitResponse{
+				{status: http.StatusTooManyRequests, resetAfter: 0.2, scope: scopeGlobal},
+				{bucket: "a", remaining: 4, resetAfter: 1},
+			},
+			paths:    []string{"/channels/1/messages", "/guilds/1/bans/2"},
+			requests: 3,
+			wait:     200 * time.Millisecond,
+			limits:   1,
+			global:   true,
+		},
+		{
+			name: "retry_after in the body",
+			responses: []rateLimitResponse{
+				{status: http.StatusTooManyRequests, bucket: "a", resetAfter: 0.2, scope: scopeUser, bodyOnly: true},
+				{bucket: "a", remaining: 4, resetAfter: 1},
+			},
+			paths:    []string{"/channels/1/messages"},
+			requests: 2,
+			wait:     200 * time.Millisecond,
+			limits:   1,
+		},
+		{
+			name: "server errors",
+			responses: []rateLimitResponse{
+				{status: http.StatusServiceUnavailable},
+				{status: http.StatusGatewayTimeout},
+				{bucket: "a", remaining: 4, resetAfter: 1},
+			},
+			method:   http.MethodGet,
+			paths:    []string{"/channels/1/messages"},
+			requests: 3,
+			wait:     150 * time.Millisecond,
+		},
+		{
+			// the message may have been created before the gateway timed out
+			name:      "server errors of requests which aren't idempotent",
+			responses: []rateLimitResponse{{status: http.StatusGatewayTimeout}},
+			paths:     []string{"/channels/1/messages"},
+		},
+		{
+			name:      "attempts exhausted",
+			responses: []rateLimitResponse{{status: http.StatusBadGateway}},
+			method:    http.MethodDelete,
+			paths:     []string{"/channels/1/messages/2"},
+			requests:  5,
+			wait:      750 * time.Millisecond,
+		},
+		{
+			name:      "errors which aren't retried",
+			responses: []rateLimitResponse{{status: http.StatusInternalServerError}},
+			paths:     []string{"/channels/1/messages"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			manager, requests := newTestServer(t, test.responses...)
+			var limits []*RateLimit
+			manager.OnRateLimit = func(limit *RateLimit) {
+				limits = append(limits, limit)
+			}
+
+			method := test.method
+			if method == "" {
+				method = http.MethodPost
+			}
+
+			start := time.Now()
+			for _, path := range test.paths {
+				err := manager.Do(method, path, nil, nil)
+				if _, failed := err.(*APIError); err != nil && !failed {
+					t.Fatal(err)
+				}
+			}
+			elapsed := time.Since(start)
+
+			expected := test.requests
+			if expected == 0 {
+				expected = len(test.paths)
+			}
+			if int(atomic.LoadInt32(requests)) != expected {
+				t.Errorf("expected %d requests, got %d", expected, *requests)
+			}
+			if len(limits) != test.limits {
+				t.Fatalf("expected %d rate limits, got %d", test.limits, len(limits))
+			}
+			for _, limit := range limits {
+				if limit.Global != test.global || limit.RetryAfter != 200*time.Millisecond {
+					t.Errorf("unexpected rate limit: %#v", limit)
+				}
+			}
+			if elapsed < test.wait || elapsed > test.wait+150*time.Millisecond {
+				t.Errorf("expected to wait %s, waited %s", test.wait, elapsed)
+			}
+		})
+	}
+
+	t.Run("shared buckets", func(t *testing.T) {
+		manager, _ := newTestServer(t, rateLimitResponse{bucket: "a", remaining: 4, resetAfter: 1})
+		for _, path := range []string{"/channels/1/messages", "/channels/1/messages/2/crosspost", "/channels/2/messages"} {
+			if err := manager.Do(http.MethodPost, path, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		first := manager.GetBucket(ParseRoute(http.MethodPost, "/channels/1/messages"))
+		if manager.GetBucket(ParseRoute(http.MethodPost, "/channels/1/messages/3/crosspost")) != first {
+			t.Error("routes reporting the same bucket hash don't share a bucket")
+		}
+		if manager.GetBucket(ParseRoute(http.MethodPost, "/channels/2/messages")) == first {
+			t.Error("different major parameters share a bucket")
+		}
+		if first.Limit != 5 || first.Remaining != 4 {
+			t.Errorf("unexpected bucket state: limit %d, remaining %d", first.Limit, first.Remaining)
+		}
+
+		store := manager.store.(*MemoryStore)
+		if len(store.hashes) != 2 || store.hashes["POST /channels/:id/messages"] != "a" {
+			t.Errorf("hashes aren't kept per route template: %v", store.hashes)
+		}
+	})
+
+	t.Run("idle buckets", func(t *testing.T) {
+		manager, _ := newTestServer(t, rateLimitResponse{bucket: "a", remaining: 4, resetAfter: 1})
+		if err := manager.Do(http.MethodPost, "/channels/1/messages", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		bucket := manager.GetBucket(ParseRoute(http.MethodPost, "/channels/1/messages"))
+		bucket.lastUsed = time.Now().Add(-2 * bucketIdleTimeout)
+		manager.lastSweep = time.Time{}
+		if manager.GetBucket(ParseRoute(http.MethodPost, "/channels/1/messages")) == bucket {
+			t.Error("the idle bucket was not dropped")
+		}
+	})
+}
+
+func TestRetries(t *testing.T) {
+	t.Run("multipart bodies are replayed", func(t *testing.T) {
+		var files []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			file, _, err := r.FormFile("files[0]")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			content, _ := ioutil.ReadAll(file)
+			files = append(files, string(content))
+
+			if len(files) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`{"id": "1"}`))
+		}))
+		defer server.Close()
+
+		manager := NewRestManager("token", WithBaseURL(server.URL))
+		manager.Retry.Backoff = time.Millisecond
+
+		var message struct {
+			ID string `json:"id"`
+		}
+		file := File{Name: "file.txt", Reader: strings.NewReader("hello")}
+		err := manager.DoWithOptions(context.Background(), http.MethodPost, "/channels/1/messages", []byte(`{}`), &message, WithFiles(file), WithServerErrorRetries())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if message.ID != "1" || len(files) != 2 || files[0] != "hello" || files[1] != "hello" {
+			t.Errorf("unexpected files sent: %q", files)
+		}
+	})
+
+	t.Run("retries keep their turn", func(t *testing.T) {
+		var mu sync.Mutex
+		var received []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			received = append(received, r.URL.Query().Get("n"))
+			if len(received) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		manager := NewRestManager("token", WithBaseURL(server.URL))
+		manager.Retry.Backoff = 100 * time.Millisecond
+		manager.Retry.Jitter = 0
+
+		done := make(chan error)
+		go func() {
+			done <- manager.Do(http.MethodGet, "/channels/1/messages?n=first", nil, nil)
+		}()
+		// the second request is queued while the first one waits to be retried
+		time.Sleep(50 * time.Millisecond)
+		if err := manager.Do(http.MethodGet, "/channels/1/messages?n=second", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Join(received, ",") != "first,first,second" {
+			t.Errorf("the requests were sent out of order: %v", received)
+		}
+	})
+
+	t.Run("backoff", func(t *testing.T) {
+		policy := RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond, Jitter: 0.5}
+		for attempt, max := range []time.Duration{100, 200, 300, 300} {
+			max *= time.Millisecond
+			delay := policy.backoff(attempt + 1)
+			if delay > max || delay < max/2 {
+				t.Errorf("attempt %d: delay %s isn't between %s and %s", attempt+1, delay, max/2, max)
+			}
+		}
+	})
 }
@@ -0,0 +1,75 @@
+package rest
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy decides how requests failing with a rate limit or a server error are retried
+type RetryPolicy struct {
+	MaxAttempts int           // the attempts made before the error is returned, including the first one
+	Backoff     time.Duration // the delay before retrying a server error, doubled on every attempt
+	MaxBackoff  time.Duration // the maximum delay before retrying a server error
+	Jitter      float64       // the fraction of the delay randomized, so requests failing together don't retry together
+}
+
+// DefaultRetryPolicy is the retry policy of new rest managers
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	Backoff:     500 * time.Millisecond,
+	MaxBackoff:  10 * time.Second,
+	Jitter:      0.5,
+}
+
+// backoff returns the delay before retrying a server error after the attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.MaxBackoff
+	if attempt < 16 {
+		delay = p.Backoff << uint(attempt-1)
+		if delay > p.MaxBackoff && p.MaxBackoff > 0 {
+			delay = p.MaxBackoff
+		}
+	}
+
+	if p.Jitter > 0 && delay > 0 {
+		delay -= time.Duration(rand.Float64() * p.Jitter * float64(delay))
+	}
+
+	return delay
+}
+
+// retryable returns whether a request failing with the status code may succeed when sent again. Rate
+// limited requests weren't handled and are always retried, while server errors are only retried for
+// idempotent methods unless serverErrors is set
+func retryable(status int, method string, serverErrors bool) bool {
+	switch status {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return serverErrors || idempotent(method)
+	}
+
+	return false
+}
+
+// idempotent returns whether sending a request with the method several times has the effect of sending it once
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
+
+// RateLimit is reported whenever a request hits a rate limit
+type RateLimit struct {
+	Method     string
+	URL        string
+	Bucket     string        // the bucket hash, empty for global rate limits
+	Scope      string        // user, global or shared
+	Global     bool          // whether every request is rate limited
+	RetryAfter time.Duration // the time to wait before the request is sent again
+	Attempt    int           // the attempt that hit the rate limit, starting from 1
+}
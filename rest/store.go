@@ -0,0 +1,125 @@
+package rest
+
+import (
+	"sync"
+	"time"
+)
+
+// BucketState is the rate limit state of a bucket
+type BucketState struct {
+	Remaining int64     `json:"remaining"`
+	Limit     int64     `json:"limit"` // 0 until a response tells it
+	ResetTime time.Time `json:"reset_time"`
+	Pending   bool      `json:"pending"` // whether the reset is assumed until the response of the window tells it
+}
+
+// staleBucket is the time after its reset a bucket state is dropped by a store, not to pile up states
+// of every major parameter
+const staleBucket = time.Minute
+
+// newBucketState is the state of a bucket nothing is known about yet, allowing a single request to
+// discover its limits
+var newBucketState = BucketState{Remaining: 1}
+
+// RateLimitStore keeps the rate limit state of a token. Managers sharing a store, even from several
+// processes, respect the rate limits of the token together
+type RateLimitStore interface {
+	// Bucket returns the state of a bucket
+	Bucket(key string) (BucketState, error)
+	// UpdateBucket updates the state of a bucket atomically
+	UpdateBucket(key string, update func(*BucketState)) error
+	// BucketHash returns the bucket hash Discord reported for a route, an empty string if unknown
+	BucketHash(route string) (string, error)
+	// SetBucketHash records the bucket hash of a route, with every response reporting it so stores may
+	// expire the hashes no longer in use
+	SetBucketHash(route, hash string) error
+	// Global returns the time every request is rate limited until
+	Global() (time.Time, error)
+	// SetGlobal rate limits every request until the time, unless they already are for longer
+	SetGlobal(until time.Time) error
+}
+
+// MemoryStore is a RateLimitStore keeping the rate limits in memory, the default one
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]BucketState
+	hashes  map[string]string
+	global  time.Time
+	pruned  time.Time // when stale bucket states were last dropped
+}
+
+// NewMemoryStore returns an empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]BucketState),
+		hashes:  make(map[string]string),
+	}
+}
+
+func (s *MemoryStore) Bucket(key string) (BucketState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.buckets[key]
+	if !ok {
+		return newBucketState, nil
+	}
+
+	return state, nil
+}
+
+func (s *MemoryStore) UpdateBucket(key string, update func(*BucketState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.buckets[key]
+	if !ok {
+		state = newBucketState
+	}
+	update(&state)
+	s.buckets[key] = state
+
+	// the states of buckets are kept per major parameter, stale ones are dropped not to pile up
+	if time.Since(s.pruned) > staleBucket {
+		s.pruned = time.Now()
+		for key, state := range s.buckets {
+			if time.Since(state.ResetTime) > staleBucket {
+				delete(s.buckets, key)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) BucketHash(route string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.hashes[route], nil
+}
+
+func (s *MemoryStore) SetBucketHash(route, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hashes[route] = hash
+	return nil
+}
+
+func (s *MemoryStore) Global() (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.global, nil
+}
+
+func (s *MemoryStore) SetGlobal(until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if until.After(s.global) {
+		s.global = until
+	}
+	return nil
+}
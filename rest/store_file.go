@@ -0,0 +1,224 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// staleHash is the time a bucket hash is kept by a file store since the last response of its route
+// reported it
+const staleHash = time.Hour
+
+// FileStore is a RateLimitStore keeping the rate limits in a file, shared by every process on the host
+// using the same file. The state is cached in memory, and the file is locked while it's read or written.
+// File stores aren't supported on windows
+type FileStore struct {
+	mu         sync.Mutex // serializes the operations of this process, the file lock being held meanwhile
+	file       *os.File
+	state      fileState
+	generation uint64 // the generation of the cached state, incremented by every write to the file
+	loaded     bool
+}
+
+// fileState is the content of a file store, after the generation line
+type fileState struct {
+	Buckets map[string]BucketState `json:"buckets"`
+	Hashes  map[string]bucketHash  `json:"bucket_hashes"` // by route template, which holds no token
+	Global  time.Time              `json:"global"`
+}
+
+// bucketHash is a bucket hash kept by a file store
+type bucketHash struct {
+	Hash string    `json:"hash"`
+	Set  time.Time `json:"set"`
+}
+
+// NewFileStore returns a store kept in the file at the path, created if it doesn't exist. The file stays
+// open until the store is closed
+func NewFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	// file locks aren't available on every platform
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := unlockFile(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &FileStore{file: file}, nil
+}
+
+// Close closes the file of the store
+func (s *FileStore) Close() error {
+	return s.file.Close()
+}
+
+// view calls fn with the state of the store while holding the lock of the file. The state is written
+// back when modified
+func (s *FileStore) view(modify bool, fn func(*fileState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := lockFile(s.file); err != nil {
+		return err
+	}
+	defer unlockFile(s.file)
+
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	fn(&s.state)
+	if !modify {
+		return nil
+	}
+
+	return s.write()
+}
+
+// load reads the state from the file, unless the generation the file starts with shows it's the one
+// already cached
+func (s *FileStore) load() error {
+	header := make([]byte, 21)
+	n, err := s.file.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	generation, ok := parseGeneration(header[:n])
+	if ok && s.loaded && generation == s.generation {
+		return nil
+	}
+
+	content, err := ioutil.ReadAll(io.NewSectionReader(s.file, 0, 1<<62))
+	if err != nil {
+		return err
+	}
+
+	// files which can't be read, such as files written by older versions, start over from an empty state
+	state := fileState{}
+	if ok {
+		_ = json.Unmarshal(content[bytes.IndexByte(content, '\n')+1:], &state)
+	}
+	if state.Buckets == nil {
+		state.Buckets = make(map[string]BucketState)
+	}
+	if state.Hashes == nil {
+		state.Hashes = make(map[string]bucketHash)
+	}
+
+	s.state = state
+	s.generation = generation
+	s.loaded = true
+	return nil
+}
+
+// write writes the state to the file with the next generation, dropping stale buckets and hashes
+func (s *FileStore) write() error {
+	for key, bucket := range s.state.Buckets {
+		if time.Since(bucket.ResetTime) > staleBucket {
+			delete(s.state.Buckets, key)
+		}
+	}
+	for route, hash := range s.state.Hashes {
+		if time.Since(hash.Set) > staleHash {
+			delete(s.state.Hashes, route)
+		}
+	}
+
+	state, err := json.Marshal(&s.state)
+	if err != nil {
+		return err
+	}
+
+	s.generation++
+	content := strconv.AppendUint(nil, s.generation, 10)
+	content = append(append(content, '\n'), state...)
+
+	if _, err := s.file.WriteAt(content, 0); err != nil {
+		return err
+	}
+	return s.file.Truncate(int64(len(content)))
+}
+
+// parseGeneration parses the generation line a file starts with
+func parseGeneration(header []byte) (uint64, bool) {
+	end := bytes.IndexByte(header, '\n')
+	if end < 0 {
+		return 0, false
+	}
+
+	generation, err := strconv.ParseUint(string(header[:end]), 10, 64)
+	return generation, err == nil
+}
+
+func (s *FileStore) Bucket(key string) (state BucketState, err error) {
+	err = s.view(false, func(f *fileState) {
+		var ok bool
+		if state, ok = f.Buckets[key]; !ok {
+			state = newBucketState
+		}
+	})
+	return
+}
+
+func (s *FileStore) UpdateBucket(key string, update func(*BucketState)) error {
+	return s.view(true, func(f *fileState) {
+		state, ok := f.Buckets[key]
+		if !ok {
+			state = newBucketState
+		}
+		update(&state)
+		f.Buckets[key] = state
+	})
+}
+
+func (s *FileStore) BucketHash(route string) (hash string, err error) {
+	err = s.view(false, func(f *fileState) {
+		hash = f.Hashes[route].Hash
+	})
+	return
+}
+
+func (s *FileStore) SetBucketHash(route, hash string) error {
+	// a known hash is only written again once it's half stale, not to rewrite the file with every response
+	var fresh bool
+	err := s.view(false, func(f *fileState) {
+		known, ok := f.Hashes[route]
+		fresh = ok && known.Hash == hash && time.Since(known.Set) < staleHash/2
+	})
+	if err != nil || fresh {
+		return err
+	}
+
+	return s.view(true, func(f *fileState) {
+		f.Hashes[route] = bucketHash{Hash: hash, Set: time.Now()}
+	})
+}
+
+func (s *FileStore) Global() (until time.Time, err error) {
+	err = s.view(false, func(f *fileState) {
+		until = f.Global
+	})
+	return
+}
+
+func (s *FileStore) SetGlobal(until time.Time) error {
+	return s.view(true, func(f *fileState) {
+		if until.After(f.Global) {
+			f.Global = until
+		}
+	})
+}
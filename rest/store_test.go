@@ -0,0 +1,204 @@
+package rest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStores(t *testing.T) {
+	file, err := NewFileStore(filepath.Join(t.TempDir(), "ratelimits.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	stores := []struct {
+		name  string
+		store RateLimitStore
+	}{
+		{"memory", NewMemoryStore()},
+		{"file", file},
+	}
+
+	for _, test := range stores {
+		store := test.store
+		t.Run(test.name, func(t *testing.T) {
+			state, err := store.Bucket("a")
+			if err != nil || state != newBucketState {
+				t.Fatalf("unexpected state of an unknown bucket: %#v, %v", state, err)
+			}
+
+			reset := time.Now().Add(time.Second).Round(0)
+			err = store.UpdateBucket("a", func(state *BucketState) {
+				state.Remaining, state.Limit, state.ResetTime = 4, 5, reset
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			state, err = store.Bucket("a")
+			if err != nil || state.Remaining != 4 || state.Limit != 5 || !state.ResetTime.Equal(reset) {
+				t.Errorf("unexpected state: %#v, %v", state, err)
+			}
+
+			if err := store.SetBucketHash("POST /channels/1/messages", "hash"); err != nil {
+				t.Fatal(err)
+			}
+			if hash, err := store.BucketHash("POST /channels/1/messages"); err != nil || hash != "hash" {
+				t.Errorf("unexpected hash: %q, %v", hash, err)
+			}
+
+			later := time.Now().Add(time.Minute).Round(0)
+			store.SetGlobal(later)
+			store.SetGlobal(later.Add(-time.Second))
+			if global, err := store.Global(); err != nil || !global.Equal(later) {
+				t.Errorf("unexpected global rate limit: %s, %v", global, err)
+			}
+		})
+	}
+}
+
+func TestSharedStore(t *testing.T) {
+	const limit, requests = 3, 9
+	window := 200 * time.Millisecond
+
+	// the server allows 3 requests every 200ms, answering with 429 once they're exhausted
+	var mu sync.Mutex
+	var reset time.Time
+	var remaining, limited int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if now := time.Now(); now.After(reset) {
+			reset = now.Add(window)
+			remaining = limit
+		}
+
+		h := w.Header()
+		h.Set("X-RateLimit-Bucket", "a")
+		h.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+		h.Set("X-RateLimit-Reset-After", strconv.FormatFloat(time.Until(reset).Seconds(), 'f', 3, 64))
+		if remaining == 0 {
+			limited++
+			h.Set("X-RateLimit-Remaining", "0")
+			h.Set("Retry-After", strconv.FormatFloat(time.Until(reset).Seconds(), 'f', 3, 64))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		remaining--
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// every manager stands for a process, sharing the file
+	path := filepath.Join(t.TempDir(), "ratelimits.json")
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		store, err := NewFileStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+		manager := NewRestManager("token", WithBaseURL(server.URL), WithStore(store))
+
+		for j := 0; j < requests/3; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := manager.Do(http.MethodPost, "/channels/1/messages", nil, nil); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	if limited != 0 {
+		t.Errorf("the managers hit %d rate limits", limited)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Bucket", "webhook")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "ratelimits.json")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	t.Run("tokens", func(t *testing.T) {
+		manager := NewRestManager("", WithBaseURL(server.URL), WithStore(store))
+		if err := manager.Do(http.MethodPost, "/webhooks/3/secret-token", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(content), "secret-token") || !strings.Contains(string(content), "webhook") {
+			t.Errorf("unexpected content: %s", content)
+		}
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		other, err := NewFileStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer other.Close()
+
+		if err := other.SetBucketHash("GET /guilds/:id", "guild"); err != nil {
+			t.Fatal(err)
+		}
+		if hash, err := store.BucketHash("GET /guilds/:id"); err != nil || hash != "guild" {
+			t.Errorf("a hash set by another store was not read: %q, %v", hash, err)
+		}
+	})
+
+	t.Run("hashes in use", func(t *testing.T) {
+		// the hash recorded by the webhook request is reported again, and kept for another hour
+		store.view(true, func(f *fileState) {
+			hash := f.Hashes["POST /webhooks/:id/:token"]
+			hash.Set = time.Now().Add(-staleHash + time.Minute)
+			f.Hashes["POST /webhooks/:id/:token"] = hash
+		})
+
+		manager := NewRestManager("", WithBaseURL(server.URL), WithStore(store))
+		if err := manager.Do(http.MethodPost, "/webhooks/3/secret-token", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		store.view(false, func(f *fileState) {
+			if hash := f.Hashes["POST /webhooks/:id/:token"]; hash.Hash != "webhook" || time.Since(hash.Set) > time.Minute {
+				t.Errorf("the hash was not refreshed: %#v", hash)
+			}
+		})
+	})
+
+	t.Run("stale hashes", func(t *testing.T) {
+		store.view(true, func(f *fileState) {
+			f.Hashes["GET /users/:id"] = bucketHash{Hash: "user", Set: time.Now().Add(-2 * staleHash)}
+		})
+		if err := store.SetBucketHash("GET /channels/:id", "channel"); err != nil {
+			t.Fatal(err)
+		}
+		if hash, _ := store.BucketHash("GET /users/:id"); hash != "" {
+			t.Errorf("a stale hash was kept: %q", hash)
+		}
+	})
+}
@@ -1,75 +1,239 @@
 package gocord
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"math/rand"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Soumil07/gocord/cache"
 	"github.com/gorilla/websocket"
 )
 
+const (
+	// minReconnectDelay is the delay before the first reconnect attempt, doubled on every consecutive failure
+	minReconnectDelay = time.Second
+	// maxReconnectDelay caps the exponential reconnect backoff
+	maxReconnectDelay = 2 * time.Minute
+
+	// a connection may send gatewaySendLimit payloads every gatewaySendWindow before being closed with 4008
+	gatewaySendLimit  = 120
+	gatewaySendWindow = time.Minute
+	// reservedSends are kept for heartbeats, identifies and resumes, which are never held up by the limiter
+	reservedSends = 10
+)
+
+// ErrShardClosed is returned when the shard is closed while a payload waits for the send limit
+var ErrShardClosed = errors.New("the shard is closed")
+
 // Shard represents a Shard connecting to the gateway. All underlying WS connections
 // are done through Shards, with events being forwarded to the main Cluster
 type Shard struct {
 	sync.RWMutex
 	Cluster           *Cluster
 	ws                *websocket.Conn
-	heartbeatTicker   *time.Ticker
+	heartbeatStop     chan struct{} // closed when the current connection ends
 	lastHeartbeatSent int64
 	heartbeatAcked    bool // whether the heartbeat has been acknowledged
 
 	Latency int64 // heartbeat ack latency
 
 	ID         int
+	User       *User // the current user, sent in READY. Written with the shard locked, read it with RLock held
 	Token      string
-	Seq        int
-	SessionID  string
+	Encoding   Encoding     // the encoding payloads are exchanged in
 	GuildCache *cache.Cache // a mutable LRU cache with capacity set to 0
+
+	seq       int64 // the last sequence received, accessed atomically
+	sessionMu sync.Mutex
+	sessionID string // guarded by sessionMu
+
+	limiter       sendLimiter  // keeps payloads under the gateway send limit
+	membersMu     sync.RWMutex // guards the members of cached guilds
+	chunkedGuilds sync.Map     // the guilds of the session whose members were requested, by ID
+
+	memberRequests sync.Map // pending member requests, by nonce
+	voiceJoins     sync.Map // pending voice channel joins, by guild ID
+
+	retries   int           // consecutive failed connections, used for the reconnect backoff
+	closing   chan struct{} // closed when the shard is closed by the user
+	closeOnce sync.Once
 }
 
 // NewShard returns a new shard instance
 func NewShard(ID int, cluster *Cluster) *Shard {
+	encoding := cluster.Options.Encoding
+	if encoding == nil {
+		encoding = JSONEncoding
+	}
+
 	shard := &Shard{
 		Cluster:        cluster,
 		heartbeatAcked: true,
+		Encoding:       encoding,
 
 		ID:         ID,
 		Token:      cluster.Token,
 		GuildCache: cache.NewCache(0),
+
+		closing: make(chan struct{}),
 	}
 
 	return shard
 }
 
-// Connect establishes a connection with the Discord API
-func (s *Shard) Connect() (err error) {
-	// TODO: forward this to the rest API when done
+// Connect establishes a connection with the Discord API. Connect blocks until the shard is closed,
+// reconnecting with an exponential backoff whenever the connection drops. Dropped sessions are resumed
+// when possible, otherwise the shard identifies again
+func (s *Shard) Connect() error {
 	if s.Cluster.GatewayURL == "" {
 		s.Cluster.fetchRecommendedShards()
 	}
-	s.Cluster.GatewayURL = fmt.Sprintf("%s?v=%d&encoding=json", s.Cluster.GatewayURL, APIVersion)
-	s.ws, _, err = websocket.DefaultDialer.Dial(s.Cluster.GatewayURL, nil)
+
+	for {
+		err := s.connect()
+		if s.isClosing() {
+			return nil
+		}
+
+		reason, action := disconnectReason(err)
+		s.Cluster.Dispatch(shardDisconnectEvent, s, &ShardDisconnect{
+			Err:    reason,
+			Action: action,
+		})
+
+		switch action {
+		case ReconnectStop:
+			s.debugf("connection closed (%s), not reconnecting", reason)
+			return reason
+		case ReconnectIdentify:
+			s.resetSession()
+		}
+
+		delay := s.backoff()
+		s.debugf("connection closed (%s), reconnecting (%s) in %s", reason, action, delay)
+		select {
+		case <-time.After(delay):
+		case <-s.closing:
+			return nil
+		}
+	}
+}
+
+// connect dials the gateway and reads from it until the connection is closed
+func (s *Shard) connect() error {
+	url := fmt.Sprintf("%s?v=%d&encoding=%s", s.Cluster.GatewayURL, APIVersion, s.Encoding.Name())
+	var z *inflater
+	if s.Cluster.Options.Compression {
+		url += "&compress=zlib-stream"
+		z = &inflater{}
+	}
+
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
 	if err != nil {
-		panic(fmt.Sprintf("failed to connect to gateway: %s", err.Error()))
+		return fmt.Errorf("failed to connect to gateway: %s", err.Error())
 	}
-	s.ws.SetCloseHandler(func(code int, text string) error {
+
+	s.Lock()
+	// Close found no socket to close while the gateway was dialed
+	if s.isClosing() {
+		s.Unlock()
+		ws.Close()
 		return nil
-	})
+	}
+	s.ws = ws
+	s.heartbeatAcked = true
+	s.heartbeatStop = make(chan struct{})
+	stop := s.heartbeatStop
+	s.Unlock()
+	s.limiter.reset()
+
+	defer ws.Close()
+	defer close(stop)
 
-	msgChan, errChan := s.listen()
 	for {
-		select {
-		case m := <-msgChan:
-			s.onMessage(m)
-		case err := <-errChan:
-			if err == io.EOF || err == io.ErrUnexpectedEOF {
-				return nil
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		if z != nil {
+			data, err = z.Write(data)
+			if err != nil {
+				return err
+			}
+			// the payload is split across frames
+			if data == nil {
+				continue
 			}
 		}
+
+		payload := &receivePayload{}
+		err = s.Encoding.Unmarshal(data, payload)
+		if err != nil {
+			return err
+		}
+
+		err = s.onMessage(payload)
+		if err == ErrReconnectRequested {
+			return err
+		}
+		if err != nil {
+			s.debugf("error while handling payload: %s", err)
+		}
+	}
+}
+
+// backoff returns the delay before the next reconnect attempt, using an exponential backoff with jitter
+func (s *Shard) backoff() time.Duration {
+	delay := maxReconnectDelay
+	if s.retries < 8 {
+		delay = minReconnectDelay << uint(s.retries)
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+	s.retries++
+
+	// jitter between half and the full delay, so shards dropped together don't reconnect together
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)))
+}
+
+// Seq returns the last sequence received, replayed from when the session is resumed
+func (s *Shard) Seq() int {
+	return int(atomic.LoadInt64(&s.seq))
+}
+
+// SessionID returns the ID of the session, empty until READY is received
+func (s *Shard) SessionID() string {
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+
+	return s.sessionID
+}
+
+func (s *Shard) setSessionID(id string) {
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+
+	s.sessionID = id
+}
+
+// resetSession clears the session, forcing the next connection to identify instead of resuming
+func (s *Shard) resetSession() {
+	s.setSessionID("")
+	atomic.StoreInt64(&s.seq, 0)
+}
+
+func (s *Shard) isClosing() bool {
+	select {
+	case <-s.closing:
+		return true
+	default:
+		return false
 	}
 }
 
@@ -78,91 +242,242 @@ func (s *Shard) send(op int, d interface{}) error {
 	s.Lock()
 	defer s.Unlock()
 
-	return s.ws.WriteJSON(&sendPayload{
+	data, err := s.Encoding.Marshal(&sendPayload{
 		OP: op,
 		D:  d,
 	})
+	if err != nil {
+		return err
+	}
+
+	return s.ws.WriteMessage(s.Encoding.MessageType(), data)
+}
+
+// sendLimited sends a payload once the send limit of the connection allows it. Heartbeats, identifies and
+// resumes are sent with send instead, so they are never held up
+func (s *Shard) sendLimited(op int, d interface{}) error {
+	if !s.limiter.wait(s.closing) {
+		return ErrShardClosed
+	}
+
+	return s.send(op, d)
+}
+
+// sendLimiter keeps the payloads sent over a connection under the gateway send limit
+type sendLimiter struct {
+	sync.Mutex
+	resetAt   time.Time // when the current window ends
+	remaining int       // the sends left in the current window
+}
+
+// reset starts over for a new connection
+func (l *sendLimiter) reset() {
+	l.Lock()
+	defer l.Unlock()
+
+	l.resetAt = time.Time{}
+}
+
+// wait takes a send from the current window, waiting for the next window once it's used up. It returns
+// false if stop is closed first
+func (l *sendLimiter) wait(stop <-chan struct{}) bool {
+	for {
+		l.Lock()
+		now := time.Now()
+		if now.After(l.resetAt) {
+			l.resetAt = now.Add(gatewaySendWindow)
+			l.remaining = gatewaySendLimit - reservedSends
+		}
+		if l.remaining > 0 {
+			l.remaining--
+			l.Unlock()
+			return true
+		}
+		delay := l.resetAt.Sub(now)
+		l.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-stop:
+			timer.Stop()
+			return false
+		}
+	}
 }
 
 func (s *Shard) onMessage(packet *receivePayload) error {
 	// update the last sequence received
 	if packet.Seq != 0 {
-		s.Seq = packet.Seq
+		atomic.StoreInt64(&s.seq, int64(packet.Seq))
 	}
 
 	switch packet.OP {
 	case OPCodeHello:
 		var pk helloPayload
-		err := json.Unmarshal(packet.D, &pk)
+		err := s.Encoding.Unmarshal(packet.D, &pk)
 		if err != nil {
 			return err
 		}
 
 		s.debugf("heartbeat interval: %d", pk.HeartbeatInterval)
-		go s.startHeartbeat(time.Duration(pk.HeartbeatInterval) * time.Millisecond)
-
-		s.debug("sending identify payload")
-		return s.identify()
+		go s.startHeartbeat(time.Duration(pk.HeartbeatInterval)*time.Millisecond, s.heartbeatStop)
 
-	case OPCodeDispatch:
-		switch packet.T {
-		case ReadyEvent:
-			var pk readyDispatch
-			err := json.Unmarshal(packet.D, &pk)
-			if err != nil {
-				panic(err)
-			}
+		if s.SessionID() != "" {
+			return s.Resume()
+		}
 
-			var unavailableGuilds int
-			for _, guild := range pk.Guilds {
-				if guild.Unavailable {
-					unavailableGuilds++
-				}
-				s.GuildCache.Add(guild.ID, guild)
+		// waiting for the identify rate limit doesn't hold up the read loop
+		stop := s.heartbeatStop
+		go func() {
+			if err := s.identify(stop); err != nil {
+				s.debugf("error while identifying: %s", err)
 			}
-			s.debugf("%d guilds loaded, %d unavailable", s.GuildCache.Size(), unavailableGuilds)
+		}()
 
-			s.Cluster.Dispatch("ready", s)
+	case OPCodeReconnect:
+		s.debug("the gateway requested a reconnect")
+		return ErrReconnectRequested
 
-		// GUILD_CREATE is sometimes fired immediately after ready to load all lazy loaded guilds
-		case GuildCreateEvent:
-			var guild Guild
-			err := json.Unmarshal(packet.D, &guild)
-			if err != nil {
-				panic(err)
-			}
+	case OPCodeInvalidSession:
+		// d is true when the session may be resumed
+		var resumable bool
+		err := s.Encoding.Unmarshal(packet.D, &resumable)
+		if err != nil {
+			return err
+		}
 
-			// lazy loading unavailable guilds, don't dispatch GUILD_CREATE to the cluster
-			if s.GuildCache.Has(guild.ID) {
-				s.debugf("lazy loaded the guild %s", guild.Name)
-				s.GuildCache.Update(guild.ID, guild)
-			} else {
-				s.GuildCache.Add(guild.ID, guild)
-				s.Cluster.Dispatch("guildCreate", guild)
-			}
+		resume := resumable && s.SessionID() != ""
+		if !resume {
+			s.resetSession()
+		}
 
-		case MessageEvent:
-			var m *Message
-			err := json.Unmarshal(packet.D, &m)
-			if err != nil {
-				panic(err)
-			}
+		// Discord expects a random wait between 1 and 5 seconds before identifying again. The read loop
+		// goes on meanwhile, so heartbeats are still acknowledged
+		delay := time.Second + time.Duration(rand.Int63n(int64(4*time.Second)))
+		go s.reidentify(delay, resume, s.heartbeatStop)
 
-			s.Cluster.Dispatch("message", s, m)
-		}
+	case OPCodeDispatch:
+		return s.onDispatch(packet.T, packet.D)
 
 	case OPCodeHeartbeatAck:
+		s.Lock()
 		s.Latency = (time.Now().UnixNano() - s.lastHeartbeatSent) / 1000000 // nanoseconds to milliseconds
-		s.debugf("heartbeat acknowledged. latency: %d ms", s.Latency)
 		s.heartbeatAcked = true
+		s.Unlock()
+		s.debugf("heartbeat acknowledged. latency: %d ms", s.Latency)
+	}
+
+	return nil
+}
+
+// onDispatch decodes a dispatch event, updates the cache and forwards the event to the cluster
+func (s *Shard) onDispatch(name string, data []byte) error {
+	factory, ok := eventFactories[name]
+	if !ok {
+		s.debugf("unknown event %s", name)
+		return nil
+	}
+
+	event := factory()
+	err := s.Encoding.Unmarshal(data, event)
+	if err != nil {
+		return fmt.Errorf("error while decoding %s: %s", name, err.Error())
+	}
+
+	switch e := event.(type) {
+	case *Ready:
+		s.setSessionID(e.SessionID)
+		s.Lock()
+		s.User = e.User
+		s.Unlock()
+		s.retries = 0
+
+		// the guilds of a new session replace the cached ones, and are chunked again
+		s.chunkedGuilds.Range(func(id, _ interface{}) bool {
+			s.chunkedGuilds.Delete(id)
+			return true
+		})
+
+		var unavailableGuilds int
+		for _, guild := range e.Guilds {
+			if guild.Unavailable {
+				unavailableGuilds++
+			}
+			s.GuildCache.Add(guild.ID, guild)
+		}
+		s.debugf("%d guilds loaded, %d unavailable", s.GuildCache.Size(), unavailableGuilds)
+
+		s.Cluster.Dispatch("ready", s)
+
+	case *Resumed:
+		s.debugf("resumed session %s", s.SessionID())
+		s.retries = 0
+
+	// GUILD_CREATE is sometimes fired immediately after ready to load all lazy loaded guilds
+	case *GuildCreate:
+		// lazy loading unavailable guilds, don't dispatch GUILD_CREATE to the cluster
+		if cached, ok := s.GuildCache.Get(e.ID); ok {
+			s.debugf("lazy loaded the guild %s", e.Name)
+			if _, chunked := s.chunkedGuilds.Load(e.ID); chunked {
+				// the guild is available again after an outage, the members requested before are kept
+				s.membersMu.Lock()
+				members := e.Members
+				e.Members = cached.(*Guild).Members
+				e.Guild.addMembers(members)
+				s.membersMu.Unlock()
+			}
+			s.GuildCache.Update(e.ID, e.Guild)
+			s.chunkGuild(e.Guild)
+			return nil
+		}
+
+		s.GuildCache.Add(e.ID, e.Guild)
+		s.chunkGuild(e.Guild)
+		s.Cluster.Dispatch("guildCreate", *e.Guild)
+
+	case *GuildUpdate:
+		// GUILD_UPDATE doesn't include the fields only sent in GUILD_CREATE, carry them over
+		if cached, ok := s.GuildCache.Get(e.ID); ok {
+			guild := cached.(*Guild)
+			e.JoinedAt = guild.JoinedAt
+			e.Large = guild.Large
+			e.MemberCount = guild.MemberCount
+			e.Members = guild.Members
+			e.Channels = guild.Channels
+			e.Presences = guild.Presences
+		}
+		s.GuildCache.Update(e.ID, e.Guild)
+
+	case *GuildDelete:
+		if cached, ok := s.GuildCache.Get(e.ID); ok && e.Unavailable {
+			// the guild is unavailable because of an outage, and is lazy loaded once it's available again
+			cached.(*Guild).Unavailable = true
+		} else {
+			s.GuildCache.Remove(e.ID)
+			s.chunkedGuilds.Delete(e.ID)
+		}
+
+	case *GuildMembersChunk:
+		s.onMembersChunk(e)
+
+	case *VoiceStateUpdate:
+		s.onVoiceStateUpdate(e.VoiceState)
+
+	case *VoiceServerUpdate:
+		s.onVoiceServerUpdate(e)
+
+	case *MessageCreate:
+		s.Cluster.Dispatch("message", s, e.Message)
 	}
 
+	s.Cluster.Dispatch(name, s, event)
 	return nil
 }
 
 // UpdatePresence updates the shard's presence. NOTE: check UpdateGame if you just want to set the game
 func (s *Shard) UpdatePresence(presence Presence) error {
-	return s.send(OPCodeStatusUpdate, presence)
+	return s.sendLimited(OPCodeStatusUpdate, presence)
 }
 
 // UpdateGame updates the shard's presence to the given game
@@ -183,8 +498,18 @@ func (s *Shard) UpdateGamef(format string, a ...interface{}) error {
 	return s.UpdateGame(fmt.Sprintf(format, a...))
 }
 
-func (s *Shard) identify() error {
-	return s.send(OPCodeIdentify, identifyPayload{
+// identify sends an identify payload once the identify rate limit allows it, giving up once stop is closed
+func (s *Shard) identify(stop <-chan struct{}) error {
+	s.debug("waiting to identify")
+	return s.Cluster.waitIdentify(s.ID, stop, func() error {
+		s.debug("sending identify payload")
+		return s.send(OPCodeIdentify, s.identifyPayload())
+	})
+}
+
+// identifyPayload returns the payload identifying the shard
+func (s *Shard) identifyPayload() identifyPayload {
+	return identifyPayload{
 		Token: s.Token,
 		Properties: identifyProperties{
 			OS:      runtime.GOOS,
@@ -194,45 +519,63 @@ func (s *Shard) identify() error {
 		Shard:          [2]int{s.ID, s.Cluster.TotalShards},
 		Presence:       s.Cluster.Options.Presence,
 		LargeThreshold: 250,
-	})
+		Intents:        s.Cluster.intents(),
+	}
 }
 
+// reidentify resumes the session or identifies again after the delay. It gives up if the connection ends
+// first, the next connection taking over
+func (s *Shard) reidentify(delay time.Duration, resume bool, stop <-chan struct{}) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-stop:
+		return
+	}
+
+	var err error
+	if resume {
+		s.debug("invalid session, resuming")
+		err = s.Resume()
+	} else {
+		s.debug("invalid session, sending a fresh identify")
+		err = s.identify(stop)
+	}
+	if err != nil {
+		s.debugf("error while identifying again: %s", err)
+	}
+}
+
+// Resume sends a resume payload, replaying all events missed since the last sequence received
 func (s *Shard) Resume() error {
 	s.debug("resuming connection to WS")
 	return s.send(OPCodeResume, &resumeDispatch{
 		Token:     s.Token,
-		Sequence:  s.Seq,
-		SessionID: s.SessionID,
+		Sequence:  s.Seq(),
+		SessionID: s.SessionID(),
 	})
 }
 
-func (s *Shard) listen() (<-chan *receivePayload, <-chan error) {
-	msgChan, errChan := make(chan *receivePayload), make(chan error)
-
-	go func() {
-		for {
-			payload := &receivePayload{}
-			err := s.ws.ReadJSON(payload)
-			if err != nil {
-				errChan <- err
-				break
-			}
-
-			msgChan <- payload
-		}
-	}()
-
-	return msgChan, errChan
-}
-
-func (s *Shard) startHeartbeat(duration time.Duration) {
-	s.heartbeatTicker = time.NewTicker(duration)
+func (s *Shard) startHeartbeat(duration time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(duration)
+	defer ticker.Stop()
 
 	// call it once because thats how *time.Ticker works. not start ->work -> wait -> work, just
 	// start -> wait -> work
-	s.heartbeat()
-	for range s.heartbeatTicker.C {
-		s.heartbeat()
+	if s.heartbeat() != nil {
+		return
+	}
+	for {
+		select {
+		case <-ticker.C:
+			if s.heartbeat() != nil {
+				return
+			}
+		case <-stop:
+			return
+		}
 	}
 }
 
@@ -248,32 +591,47 @@ func (s *Shard) debugf(format string, a ...interface{}) {
 }
 
 func (s *Shard) heartbeat() error {
-	if !s.heartbeatAcked {
-		// heartbeat hasn't been acknowledged. close the connection and attempt to resume
-		err := s.Close()
-		if err != nil {
-			return err
-		}
+	s.Lock()
+	acked := s.heartbeatAcked
+	if acked {
+		// the heartbeat is marked as sent before sending it, so an ACK read meanwhile isn't overwritten
+		s.heartbeatAcked = false
+		s.lastHeartbeatSent = time.Now().UnixNano()
+	}
+	s.Unlock()
 
+	if !acked {
+		// heartbeat hasn't been acknowledged, the connection is a zombie. closing the socket makes the
+		// read loop return, and the connection is then resumed
 		s.debug("heartbeat not acknowledged, attempting a reconnect")
-		return s.Resume()
+		s.RLock()
+		defer s.RUnlock()
+		s.ws.Close()
+
+		return errors.New("heartbeat not acknowledged")
 	}
-	err := s.send(OPCodeHeartbeat, s.Seq)
+	err := s.send(OPCodeHeartbeat, s.Seq())
 	s.debug("heartbeat sent")
-	// sent heartbeat hasn't been acknowledged yet
-	s.heartbeatAcked = false
-	// this is to track ack latency
-	s.lastHeartbeatSent = time.Now().UnixNano()
 	return err
 }
 
-// Close gracefully closes the connection to Discord
+// Close gracefully closes the connection to Discord. A closed shard does not reconnect
 func (s *Shard) Close() error {
-	err := s.ws.Close()
+	s.closeOnce.Do(func() {
+		close(s.closing)
+	})
+
+	s.Lock()
+	defer s.Unlock()
+	if s.ws == nil {
+		return nil
+	}
+
+	err := s.ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
 	if err != nil {
+		s.ws.Close()
 		return err
 	}
-	s.heartbeatTicker.Stop()
 
-	return nil
+	return s.ws.Close()
 }
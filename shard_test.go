@@ -0,0 +1,314 @@
+package gocord
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	eventemitter "github.com/euskadi31/go-eventemitter"
+	"github.com/gorilla/websocket"
+)
+
+// newTestCluster returns a cluster connecting to the supplied gateway, without touching the Discord API
+func newTestCluster(gatewayURL string) *Cluster {
+	return &Cluster{
+		Emitter:     eventemitter.New(),
+		Token:       "token",
+		TotalShards: 1,
+		GatewayURL:  gatewayURL,
+	}
+}
+
+// newTestGateway starts a gateway stand-in, which says hello and answers identifies with a READY. Every
+// payload received but heartbeats is passed to handle, identifies before they're answered
+func newTestGateway(ready map[string]interface{}, handle func(ws *websocket.Conn, payload *receivePayload)) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		ws.WriteJSON(map[string]interface{}{"op": OPCodeHello, "d": map[string]interface{}{"heartbeat_interval": 60000}})
+		for {
+			var payload receivePayload
+			if err := ws.ReadJSON(&payload); err != nil {
+				return
+			}
+
+			switch payload.OP {
+			case OPCodeHeartbeat:
+				ws.WriteJSON(map[string]interface{}{"op": OPCodeHeartbeatAck})
+			case OPCodeIdentify:
+				handle(ws, &payload)
+				ws.WriteJSON(map[string]interface{}{"op": OPCodeDispatch, "s": 1, "t": ReadyEvent, "d": ready})
+			default:
+				handle(ws, &payload)
+			}
+		}
+	}))
+}
+
+// connectTestShard connects a shard to the gateway stand-in, and waits for it to be ready
+func connectTestShard(t *testing.T, server *httptest.Server) *Shard {
+	cluster := newTestCluster("ws" + strings.TrimPrefix(server.URL, "http"))
+	ready := make(chan struct{})
+	cluster.OnReady(func(s *Shard, r *Ready) {
+		close(ready)
+	})
+
+	shard := NewShard(0, cluster)
+	go shard.Connect()
+
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the shard did not connect")
+	}
+
+	return shard
+}
+
+func TestShardBackoff(t *testing.T) {
+	s := &Shard{}
+	for i := 0; i < 20; i++ {
+		delay := s.backoff()
+		if delay < minReconnectDelay/2 || delay > maxReconnectDelay {
+			t.Fatalf("attempt %d: delay %s out of bounds", i, delay)
+		}
+	}
+}
+
+func TestShardResume(t *testing.T) {
+	received := make(chan *receivePayload, 4)
+	connections := 0
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		connections++
+
+		ws.WriteJSON(map[string]interface{}{"op": OPCodeHello, "d": map[string]interface{}{"heartbeat_interval": 60000}})
+		for {
+			var payload receivePayload
+			if err := ws.ReadJSON(&payload); err != nil {
+				return
+			}
+			if payload.OP == OPCodeHeartbeat {
+				continue
+			}
+			received <- &payload
+
+			if connections == 1 && payload.OP == OPCodeIdentify {
+				ws.WriteJSON(map[string]interface{}{"op": OPCodeDispatch, "s": 1, "t": ReadyEvent, "d": map[string]interface{}{"session_id": "session"}})
+				ws.WriteJSON(map[string]interface{}{"op": OPCodeDispatch, "s": 2, "t": "TYPING_START", "d": map[string]interface{}{}})
+				// drop the connection, the shard should resume
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	cluster := newTestCluster("ws" + strings.TrimPrefix(server.URL, "http"))
+	shard := NewShard(0, cluster)
+	go shard.Connect()
+	defer shard.Close()
+
+	expect := func(op int) *receivePayload {
+		select {
+		case payload := <-received:
+			if payload.OP != op {
+				t.Fatalf("expected op %d, received op %d", op, payload.OP)
+			}
+			return payload
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for op %d", op)
+		}
+		return nil
+	}
+
+	expect(OPCodeIdentify)
+	payload := expect(OPCodeResume)
+
+	var resume resumeDispatch
+	if err := json.Unmarshal(payload.D, &resume); err != nil {
+		t.Fatal(err)
+	}
+	if resume.SessionID != "session" || resume.Sequence != 2 {
+		t.Errorf("unexpected resume payload: %#v", resume)
+	}
+}
+
+func TestShardInvalidSession(t *testing.T) {
+	identified := make(chan struct{}, 2)
+	identifies := 0
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		ws.WriteJSON(map[string]interface{}{"op": OPCodeHello, "d": map[string]interface{}{"heartbeat_interval": 60000}})
+		for {
+			var payload receivePayload
+			if err := ws.ReadJSON(&payload); err != nil {
+				return
+			}
+			if payload.OP != OPCodeIdentify {
+				continue
+			}
+
+			identifies++
+			identified <- struct{}{}
+			if identifies == 1 {
+				// the payload sent after the invalid session must be read while the shard waits
+				ws.WriteJSON(map[string]interface{}{"op": OPCodeInvalidSession, "d": false})
+				ws.WriteJSON(map[string]interface{}{"op": OPCodeDispatch, "s": 7, "t": "UNKNOWN", "d": map[string]interface{}{}})
+			}
+		}
+	}))
+	defer server.Close()
+
+	shard := NewShard(0, newTestCluster("ws"+strings.TrimPrefix(server.URL, "http")))
+	go shard.Connect()
+	defer shard.Close()
+
+	select {
+	case <-identified:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the shard did not identify")
+	}
+
+	deadline := time.Now().Add(500 * time.Millisecond)
+	for shard.Seq() != 7 {
+		if time.Now().After(deadline) {
+			t.Fatal("the read loop is blocked after an invalid session")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(identified) != 0 {
+		t.Error("the shard identified again without waiting")
+	}
+
+	select {
+	case <-identified:
+	case <-time.After(6 * time.Second):
+		t.Fatal("the shard did not identify again")
+	}
+}
+
+func TestShardCloseCodes(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		ws.WriteJSON(map[string]interface{}{"op": OPCodeHello, "d": map[string]interface{}{"heartbeat_interval": 60000}})
+		ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(CloseAuthenticationFailed, "Authentication failed."))
+		ws.ReadMessage()
+	}))
+	defer server.Close()
+
+	cluster := newTestCluster("ws" + strings.TrimPrefix(server.URL, "http"))
+	disconnects := make(chan *ShardDisconnect, 1)
+	cluster.OnShardDisconnect(func(s *Shard, d *ShardDisconnect) {
+		disconnects <- d
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- NewShard(0, cluster).Connect()
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrAuthenticationFailed) {
+			t.Errorf("expected an authentication error, received %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the shard did not stop")
+	}
+
+	d := <-disconnects
+	if d.Action != ReconnectStop {
+		t.Errorf("expected the stop action, received %s", d.Action)
+	}
+}
+
+func TestShardCloseWhileDialing(t *testing.T) {
+	dialing, closed := make(chan struct{}), make(chan struct{})
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the handshake is held until the shard is closed
+		close(dialing)
+		<-closed
+
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		ws.WriteJSON(map[string]interface{}{"op": OPCodeHello, "d": map[string]interface{}{"heartbeat_interval": 60000}})
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	shard := NewShard(0, newTestCluster("ws"+strings.TrimPrefix(server.URL, "http")))
+	done := make(chan error)
+	go func() {
+		done <- shard.Connect()
+	}()
+
+	<-dialing
+	shard.Close()
+	close(closed)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the shard stayed connected after being closed")
+	}
+}
+
+func TestDisconnectReason(t *testing.T) {
+	tests := []struct {
+		err    error
+		action ReconnectAction
+	}{
+		{&websocket.CloseError{Code: CloseUnknownError}, ReconnectResume},
+		{&websocket.CloseError{Code: CloseSessionTimedOut}, ReconnectIdentify},
+		{&websocket.CloseError{Code: CloseShardingRequired}, ReconnectStop},
+		{&websocket.CloseError{Code: CloseDisallowedIntents}, ReconnectStop},
+		{&websocket.CloseError{Code: websocket.CloseAbnormalClosure}, ReconnectResume},
+		{ErrReconnectRequested, ReconnectResume},
+	}
+
+	for _, test := range tests {
+		if _, action := disconnectReason(test.err); action != test.action {
+			t.Errorf("%s: expected %s, received %s", test.err, test.action, action)
+		}
+	}
+}
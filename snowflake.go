@@ -0,0 +1,29 @@
+package gocord
+
+import (
+	"strconv"
+	"time"
+)
+
+// DiscordEpoch is the first millisecond of 2015, the epoch of the timestamps in snowflakes
+const DiscordEpoch = 1420070400000
+
+// SnowflakeTime returns when the object a snowflake identifies was created
+func SnowflakeTime(id string) (time.Time, error) {
+	snowflake, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	ms := int64(snowflake>>22) + DiscordEpoch
+	return time.Unix(0, ms*int64(time.Millisecond)), nil
+}
+
+// snowflakeLess returns whether a snowflake is lower than another
+func snowflakeLess(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+
+	return a < b
+}
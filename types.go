@@ -1,17 +1,38 @@
 package gocord
 
-import "encoding/json"
-
 type gatewayPayload struct {
-	URL    string `json:"url"`
-	Shards int    `json:"shards"`
+	URL               string            `json:"url"`
+	Shards            int               `json:"shards"`
+	SessionStartLimit SessionStartLimit `json:"session_start_limit"`
+}
+
+// SessionStartLimit is the identify budget of a bot
+type SessionStartLimit struct {
+	Total          int `json:"total"`           // the total identifies allowed per day
+	Remaining      int `json:"remaining"`       // the identifies remaining until the limit resets
+	ResetAfter     int `json:"reset_after"`     // milliseconds until the limit resets
+	MaxConcurrency int `json:"max_concurrency"` // the number of identifies allowed every 5 seconds
 }
 
 type receivePayload struct {
-	OP  int             `json:"op"`
-	D   json.RawMessage `json:"d"`
-	Seq int             `json:"s,omitempty"`
-	T   string          `json:"t,omitempty"`
+	OP  int     `json:"op"`
+	D   rawData `json:"d"`
+	Seq int     `json:"s,omitempty"`
+	T   string  `json:"t,omitempty"`
+}
+
+// rawData keeps the data of a received payload undecoded, in the encoding of the shard, until its opcode
+// and event name tell what to decode it into
+type rawData []byte
+
+func (r *rawData) UnmarshalJSON(data []byte) error {
+	*r = append((*r)[:0], data...)
+	return nil
+}
+
+func (r *rawData) UnmarshalETF(data []byte) error {
+	*r = data
+	return nil
 }
 
 type sendPayload struct {
@@ -30,6 +51,7 @@ type identifyPayload struct {
 	Shard          [2]int             `json:"shard"`
 	Presence       Presence           `json:"presence"`
 	LargeThreshold int                `json:"large_threshold"`
+	Intents        Intents            `json:"intents,omitempty"`
 }
 
 // Presence represents a Discord presence object
@@ -55,13 +77,6 @@ type dispatch struct {
 	T string      `json:"t"`
 }
 
-type readyDispatch struct {
-	Version   int         `json:"v"`
-	User      interface{} `json:"user"`   // TODO: user type
-	Guilds    []*Guild    `json:"guilds"` // TODO: guild type
-	SessionID string      `json:"session_id"`
-}
-
 type resumeDispatch struct {
 	Token     string `json:"token"`
 	SessionID string `json:"session_id"`
@@ -1,6 +1,7 @@
 package gocord
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -49,10 +50,15 @@ func (u *User) DefaultAvatarURL(size int) string {
 }
 
 // FetchUser fetches a user given an ID
-func (c *Cluster) FetchUser(ID string) (u *User, err error) {
+func (c *Cluster) FetchUser(ID string) (*User, error) {
+	return c.FetchUserContext(context.Background(), ID)
+}
+
+// FetchUserContext is FetchUser with a context
+func (c *Cluster) FetchUserContext(ctx context.Context, ID string) (u *User, err error) {
 	endpoint := rest.User(ID)
 
-	err = c.Rest.Do(http.MethodGet, endpoint, nil, &u)
+	err = c.Rest.DoContext(ctx, http.MethodGet, endpoint, nil, &u)
 	if err != nil {
 		return
 	}
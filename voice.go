@@ -0,0 +1,141 @@
+package gocord
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Soumil07/gocord/voice"
+)
+
+// Implements joining and leaving voice channels through the gateway
+
+// voiceJoinTimeout is the time to wait for the voice state and server of a joined voice channel
+const voiceJoinTimeout = 10 * time.Second
+
+// ErrVoiceJoinTimeout is returned when the voice state and server of a joined channel aren't received in time
+var ErrVoiceJoinTimeout = errors.New("timed out waiting for the voice server")
+
+// VoiceConnection holds the voice session of a joined voice channel, used to connect to the voice gateway
+type VoiceConnection struct {
+	GuildID   string
+	ChannelID string
+	UserID    string
+	SessionID string // sent in VOICE_STATE_UPDATE
+	Token     string // sent in VOICE_SERVER_UPDATE
+	Endpoint  string // sent in VOICE_SERVER_UPDATE
+}
+
+// Dial connects to the voice gateway and identifies
+func (v *VoiceConnection) Dial() (*voice.Conn, error) {
+	return voice.Dial(v.Endpoint, voice.Identify{
+		ServerID:  v.GuildID,
+		UserID:    v.UserID,
+		SessionID: v.SessionID,
+		Token:     v.Token,
+	})
+}
+
+// voiceJoin is a pending voice channel join, waiting for the voice state and server
+type voiceJoin struct {
+	sync.Mutex
+	connection *VoiceConnection
+	done       chan struct{}
+}
+
+// complete closes done once both the voice state and server are received
+func (j *voiceJoin) complete() {
+	if j.connection.SessionID != "" && j.connection.Endpoint != "" {
+		close(j.done)
+	}
+}
+
+type voiceStateUpdatePayload struct {
+	GuildID   string  `json:"guild_id"`
+	ChannelID *string `json:"channel_id"` // null to leave the voice channel
+	SelfMute  bool    `json:"self_mute"`
+	SelfDeaf  bool    `json:"self_deaf"`
+}
+
+// JoinVoiceChannel joins a voice channel, and waits for the voice session and server used to connect to
+// the voice gateway
+func (s *Shard) JoinVoiceChannel(guildID, channelID string, mute, deaf bool) (*VoiceConnection, error) {
+	join := &voiceJoin{
+		connection: &VoiceConnection{
+			GuildID:   guildID,
+			ChannelID: channelID,
+		},
+		done: make(chan struct{}),
+	}
+	s.voiceJoins.Store(guildID, join)
+	defer s.voiceJoins.Delete(guildID)
+
+	err := s.sendLimited(OPCodeVoiceStateUpdate, voiceStateUpdatePayload{
+		GuildID:   guildID,
+		ChannelID: &channelID,
+		SelfMute:  mute,
+		SelfDeaf:  deaf,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-join.done:
+		join.Lock()
+		defer join.Unlock()
+		return join.connection, nil
+	case <-time.After(voiceJoinTimeout):
+		return nil, ErrVoiceJoinTimeout
+	}
+}
+
+// LeaveVoiceChannel leaves the voice channel joined in the guild
+func (s *Shard) LeaveVoiceChannel(guildID string) error {
+	return s.sendLimited(OPCodeVoiceStateUpdate, voiceStateUpdatePayload{
+		GuildID: guildID,
+	})
+}
+
+// onVoiceStateUpdate completes pending joins with the session ID of the current user's voice state
+func (s *Shard) onVoiceStateUpdate(state *VoiceState) {
+	s.RLock()
+	user := s.User
+	s.RUnlock()
+	if user == nil || state.UserID != user.ID {
+		return
+	}
+
+	v, ok := s.voiceJoins.Load(state.GuildID)
+	if !ok {
+		return
+	}
+
+	join := v.(*voiceJoin)
+	join.Lock()
+	defer join.Unlock()
+	if join.connection.SessionID != "" {
+		return
+	}
+	join.connection.UserID = state.UserID
+	join.connection.SessionID = state.SessionID
+	join.complete()
+}
+
+// onVoiceServerUpdate completes pending joins with the voice server
+func (s *Shard) onVoiceServerUpdate(server *VoiceServerUpdate) {
+	v, ok := s.voiceJoins.Load(server.GuildID)
+	if !ok {
+		return
+	}
+
+	join := v.(*voiceJoin)
+	join.Lock()
+	defer join.Unlock()
+	if join.connection.Endpoint != "" {
+		return
+	}
+	join.connection.Token = server.Token
+	join.connection.Endpoint = server.Endpoint
+	join.complete()
+}
@@ -0,0 +1,205 @@
+package voice
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// Encryption modes, as documented at https://discordapp.com/developers/docs/topics/voice-connections#encrypting-and-sending-voice
+const (
+	ModeAES256GCM         = "aead_aes256_gcm_rtpsize"
+	ModeXChaCha20Poly1305 = "aead_xchacha20_poly1305_rtpsize"
+	ModeXSalsa20Lite      = "xsalsa20_poly1305_lite"
+	ModeXSalsa20Suffix    = "xsalsa20_poly1305_suffix"
+	ModeXSalsa20          = "xsalsa20_poly1305"
+)
+
+// Modes are the supported encryption modes, by order of preference
+var Modes = []string{ModeAES256GCM, ModeXChaCha20Poly1305, ModeXSalsa20Lite, ModeXSalsa20Suffix, ModeXSalsa20}
+
+const (
+	rtpHeaderSize = 12
+	// rtpVersion is the first byte of RTP headers, version 2 without padding, extension or CSRCs
+	rtpVersion = 0x80
+	// rtpPayloadType is the payload type of Opus audio
+	rtpPayloadType = 0x78
+	// rtpExtension is set in the first byte of RTP headers followed by an extension
+	rtpExtension = 0x10
+)
+
+var errPacketTooShort = errors.New("voice: packet too short")
+
+// crypter encrypts and decrypts the payload of RTP packets with one of the encryption modes
+type crypter struct {
+	mode  string
+	key   [32]byte
+	aead  cipher.AEAD // set for the aead_*_rtpsize modes
+	nonce uint32      // incremented for every packet sent in the lite and aead modes
+}
+
+// chooseMode returns the preferred encryption mode supported by the voice server
+func chooseMode(supported []string) (string, error) {
+	for _, mode := range Modes {
+		for _, s := range supported {
+			if s == mode {
+				return mode, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("voice: no supported encryption mode in %v", supported)
+}
+
+func newCrypter(mode string, key [32]byte) (*crypter, error) {
+	c := &crypter{mode: mode, key: key}
+
+	var err error
+	switch mode {
+	case ModeAES256GCM:
+		var block cipher.Block
+		block, err = aes.NewCipher(key[:])
+		if err == nil {
+			c.aead, err = cipher.NewGCM(block)
+		}
+	case ModeXChaCha20Poly1305:
+		c.aead, err = chacha20poly1305.NewX(key[:])
+	case ModeXSalsa20Lite, ModeXSalsa20Suffix, ModeXSalsa20:
+	default:
+		err = fmt.Errorf("voice: unsupported encryption mode %s", mode)
+	}
+
+	return c, err
+}
+
+// encrypt encrypts an Opus frame, returning the packet to send
+func (c *crypter) encrypt(header []byte, opus []byte) ([]byte, error) {
+	packet := make([]byte, len(header), len(header)+len(opus)+64)
+	copy(packet, header)
+
+	switch c.mode {
+	case ModeAES256GCM, ModeXChaCha20Poly1305:
+		// the header is authenticated but not encrypted, and the nonce is a counter appended to the packet
+		c.nonce++
+		nonce := make([]byte, c.aead.NonceSize())
+		binary.BigEndian.PutUint32(nonce, c.nonce)
+
+		packet = c.aead.Seal(packet, nonce, opus, header)
+		return append(packet, nonce[:4]...), nil
+
+	case ModeXSalsa20Lite:
+		c.nonce++
+		var nonce [24]byte
+		binary.BigEndian.PutUint32(nonce[:], c.nonce)
+
+		packet = secretbox.Seal(packet, opus, &nonce, &c.key)
+		return append(packet, nonce[:4]...), nil
+
+	case ModeXSalsa20Suffix:
+		// a failed read would leave a zero nonce, reused by every packet
+		var nonce [24]byte
+		if _, err := rand.Read(nonce[:]); err != nil {
+			return nil, err
+		}
+
+		packet = secretbox.Seal(packet, opus, &nonce, &c.key)
+		return append(packet, nonce[:]...), nil
+	}
+
+	// xsalsa20_poly1305 uses the header as the nonce
+	var nonce [24]byte
+	copy(nonce[:], header)
+	return secretbox.Seal(packet, opus, &nonce, &c.key), nil
+}
+
+// decrypt decrypts a received RTP packet, stripping the header extension
+func (c *crypter) decrypt(packet []byte) (*Packet, error) {
+	if len(packet) < rtpHeaderSize {
+		return nil, errPacketTooShort
+	}
+
+	p := &Packet{
+		Sequence:  binary.BigEndian.Uint16(packet[2:]),
+		Timestamp: binary.BigEndian.Uint32(packet[4:]),
+		SSRC:      binary.BigEndian.Uint32(packet[8:]),
+	}
+
+	headerSize := rtpHeaderSize + int(packet[0]&0x0f)*4
+	extension := packet[0]&rtpExtension != 0
+	if len(packet) < headerSize {
+		return nil, errPacketTooShort
+	}
+
+	var opus []byte
+	var err error
+	switch c.mode {
+	case ModeAES256GCM, ModeXChaCha20Poly1305:
+		// the extension header is authenticated, while its body is encrypted
+		if extension {
+			headerSize += 4
+		}
+		if len(packet) < headerSize+c.aead.Overhead()+4 {
+			return nil, errPacketTooShort
+		}
+
+		nonce := make([]byte, c.aead.NonceSize())
+		copy(nonce, packet[len(packet)-4:])
+		opus, err = c.aead.Open(nil, nonce, packet[headerSize:len(packet)-4], packet[:headerSize])
+		if err != nil {
+			return nil, err
+		}
+		if extension {
+			length := int(binary.BigEndian.Uint16(packet[headerSize-2:])) * 4
+			if len(opus) < length {
+				return nil, errPacketTooShort
+			}
+			opus = opus[length:]
+		}
+
+	default:
+		var nonce [24]byte
+		encrypted := packet[headerSize:]
+		switch c.mode {
+		case ModeXSalsa20Lite:
+			if len(encrypted) < 4 {
+				return nil, errPacketTooShort
+			}
+			copy(nonce[:], encrypted[len(encrypted)-4:])
+			encrypted = encrypted[:len(encrypted)-4]
+		case ModeXSalsa20Suffix:
+			if len(encrypted) < 24 {
+				return nil, errPacketTooShort
+			}
+			copy(nonce[:], encrypted[len(encrypted)-24:])
+			encrypted = encrypted[:len(encrypted)-24]
+		default:
+			copy(nonce[:], packet[:rtpHeaderSize])
+		}
+
+		var ok bool
+		opus, ok = secretbox.Open(nil, encrypted, &nonce, &c.key)
+		if !ok {
+			return nil, errors.New("voice: failed to decrypt packet")
+		}
+		// the whole extension is encrypted
+		if extension {
+			if len(opus) < 4 {
+				return nil, errPacketTooShort
+			}
+			length := 4 + int(binary.BigEndian.Uint16(opus[2:]))*4
+			if len(opus) < length {
+				return nil, errPacketTooShort
+			}
+			opus = opus[length:]
+		}
+	}
+
+	p.Opus = opus
+	return p, nil
+}
@@ -0,0 +1,287 @@
+package voice
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// frameDuration is the duration of every Opus frame sent
+	frameDuration = 20 * time.Millisecond
+	// frameSamples is the number of samples in a 20ms frame at 48kHz
+	frameSamples = 960
+	// keepaliveInterval is the interval UDP keepalives are sent at, keeping NAT mappings open
+	keepaliveInterval = 5 * time.Second
+	// discoveryPacketSize is the size of IP discovery requests and responses
+	discoveryPacketSize = 74
+	// silenceFrames is the number of silence frames sent when audio stops, avoiding interpolation
+	silenceFrames = 5
+)
+
+// silenceFrame is an Opus frame of silence
+var silenceFrame = []byte{0xf8, 0xff, 0xfe}
+
+// Packet is a decrypted voice packet received from another user
+type Packet struct {
+	SSRC      uint32
+	Sequence  uint16
+	Timestamp uint32
+	Opus      []byte
+}
+
+// UDPConn sends and receives Opus audio over UDP. Frames written to OpusSend are sent every 20ms, and
+// frames received are sent on OpusRecv
+type UDPConn struct {
+	OpusSend chan []byte
+	OpusRecv chan *Packet
+
+	conn    *net.UDPConn
+	voice   *Conn
+	ssrc    uint32
+	crypter *crypter
+
+	sequence  uint16
+	timestamp uint32
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// OpenUDP connects to the voice server sent in the ready payload, performs IP discovery and selects the
+// protocol, finishing the handshake. The preferred encryption mode supported by the voice server is used
+func (c *Conn) OpenUDP() (*UDPConn, error) {
+	mode, err := chooseMode(c.Ready.Modes)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(c.Ready.IP, strconv.Itoa(c.Ready.Port)))
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, fmt.Errorf("voice: failed to connect to the voice server: %s", err.Error())
+	}
+
+	ip, port, err := discoverIP(conn, c.Ready.SSRC)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	description, err := c.SelectProtocol(ip, port, mode)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	crypter, err := newCrypter(description.Mode, description.SecretKey)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	u := &UDPConn{
+		OpusSend: make(chan []byte, 2),
+		OpusRecv: make(chan *Packet, 2),
+
+		conn:    conn,
+		voice:   c,
+		ssrc:    c.Ready.SSRC,
+		crypter: crypter,
+		closed:  make(chan struct{}),
+	}
+
+	go u.sendLoop()
+	go u.recvLoop()
+	go u.keepalive()
+
+	return u, nil
+}
+
+// discoverIP sends an IP discovery request, returning the external address and port of the connection
+func discoverIP(conn *net.UDPConn, ssrc uint32) (string, int, error) {
+	request := make([]byte, discoveryPacketSize)
+	binary.BigEndian.PutUint16(request, 0x1)
+	binary.BigEndian.PutUint16(request[2:], discoveryPacketSize-4)
+	binary.BigEndian.PutUint32(request[4:], ssrc)
+
+	_, err := conn.Write(request)
+	if err != nil {
+		return "", 0, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	response := make([]byte, discoveryPacketSize)
+	n, err := conn.Read(response)
+	if err != nil {
+		return "", 0, fmt.Errorf("voice: IP discovery failed: %s", err.Error())
+	}
+	if n < discoveryPacketSize || binary.BigEndian.Uint16(response) != 0x2 {
+		return "", 0, errors.New("voice: invalid IP discovery response")
+	}
+
+	// the address is null terminated, followed by the port
+	address := response[8 : discoveryPacketSize-2]
+	if i := bytes.IndexByte(address, 0); i >= 0 {
+		address = address[:i]
+	}
+	port := binary.BigEndian.Uint16(response[discoveryPacketSize-2:])
+
+	return string(address), int(port), nil
+}
+
+// Close stops sending and receiving audio, and closes the UDP connection
+func (u *UDPConn) Close() error {
+	var err error
+	u.closeOnce.Do(func() {
+		close(u.closed)
+		err = u.conn.Close()
+	})
+
+	return err
+}
+
+// Speaking sends a speaking update, required before sending audio
+func (u *UDPConn) Speaking(speaking bool) error {
+	return u.voice.Speaking(u.ssrc, speaking)
+}
+
+func (u *UDPConn) sendLoop() {
+	ticker := time.NewTicker(frameDuration)
+	defer ticker.Stop()
+
+	header := make([]byte, rtpHeaderSize)
+	header[0] = rtpVersion
+	header[1] = rtpPayloadType
+	binary.BigEndian.PutUint32(header[8:], u.ssrc)
+
+	send := func(opus []byte) error {
+		binary.BigEndian.PutUint16(header[2:], u.sequence)
+		binary.BigEndian.PutUint32(header[4:], u.timestamp)
+		u.sequence++
+		u.timestamp += frameSamples
+
+		packet, err := u.crypter.encrypt(header, opus)
+		if err != nil {
+			return err
+		}
+		_, err = u.conn.Write(packet)
+		return err
+	}
+
+	var speaking bool
+	var silence int // silence frames left to send once audio stops
+	for {
+		// the ticker is only waited on while speaking, to pace frames and detect when audio stops
+		var tick <-chan time.Time
+		if speaking {
+			tick = ticker.C
+		}
+
+		select {
+		case opus, ok := <-u.OpusSend:
+			if !ok {
+				return
+			}
+			if !speaking {
+				speaking = true
+				u.Speaking(true)
+			}
+			silence = silenceFrames
+
+			select {
+			case <-ticker.C:
+			case <-u.closed:
+				return
+			}
+			if send(opus) != nil {
+				u.Close()
+				return
+			}
+
+		case <-tick:
+			// a frame may have been written at the same time as the tick
+			select {
+			case opus, ok := <-u.OpusSend:
+				if !ok {
+					return
+				}
+				silence = silenceFrames
+				if send(opus) != nil {
+					u.Close()
+					return
+				}
+				continue
+			default:
+			}
+
+			// audio stopped, send silence so clients don't interpolate
+			send(silenceFrame)
+			silence--
+			if silence == 0 {
+				speaking = false
+				u.Speaking(false)
+			}
+
+		case <-u.closed:
+			return
+		}
+	}
+}
+
+func (u *UDPConn) recvLoop() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := u.conn.Read(buf)
+		if err != nil {
+			u.Close()
+			return
+		}
+
+		// only RTP packets of Opus audio are handled, ignoring RTCP and keepalives
+		if n < rtpHeaderSize || buf[0]&0xc0 != rtpVersion || buf[1]&0x7f != rtpPayloadType {
+			continue
+		}
+
+		packet, err := u.crypter.decrypt(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		select {
+		case u.OpusRecv <- packet:
+		case <-u.closed:
+			return
+		}
+	}
+}
+
+func (u *UDPConn) keepalive() {
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+
+	var counter uint64
+	packet := make([]byte, 8)
+	for {
+		select {
+		case <-ticker.C:
+			binary.LittleEndian.PutUint64(packet, counter)
+			counter++
+			if _, err := u.conn.Write(packet); err != nil {
+				return
+			}
+		case <-u.closed:
+			return
+		}
+	}
+}
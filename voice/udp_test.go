@@ -0,0 +1,154 @@
+package voice
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCrypter(t *testing.T) {
+	var key [32]byte
+	copy(key[:], "a secret key of thirty-two bytes")
+	header := []byte{rtpVersion, rtpPayloadType, 0, 1, 0, 0, 3, 192, 0, 0, 0, 10}
+	opus := []byte("an opus frame")
+
+	for _, mode := range Modes {
+		t.Run(mode, func(t *testing.T) {
+			sender, err := newCrypter(mode, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			receiver, _ := newCrypter(mode, key)
+
+			for i := 0; i < 2; i++ {
+				encrypted, err := sender.encrypt(header, opus)
+				if err != nil {
+					t.Fatal(err)
+				}
+				packet, err := receiver.decrypt(encrypted)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if packet.SSRC != 10 || packet.Sequence != 1 || packet.Timestamp != 960 || !bytes.Equal(packet.Opus, opus) {
+					t.Errorf("unexpected packet: %#v", packet)
+				}
+			}
+		})
+	}
+
+	t.Run("header extension", func(t *testing.T) {
+		c, _ := newCrypter(ModeAES256GCM, key)
+		extended := append([]byte{rtpVersion | rtpExtension}, header[1:]...)
+		extended = append(extended, 0xbe, 0xde, 0, 1)
+
+		nonce := make([]byte, c.aead.NonceSize())
+		nonce[3] = 1
+		plaintext := append([]byte{1, 2, 3, 4}, opus...)
+		packet := c.aead.Seal(append([]byte{}, extended...), nonce, plaintext, extended)
+		packet = append(packet, nonce[:4]...)
+
+		p, err := c.decrypt(packet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(p.Opus, opus) {
+			t.Errorf("the extension was not stripped: %q", p.Opus)
+		}
+	})
+}
+
+// newTestVoiceServer starts a loopback UDP voice server answering IP discovery, decrypting packets
+// received and echoing them back
+func newTestVoiceServer(t *testing.T, c *crypter) (*net.UDPConn, <-chan *Packet) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan *Packet, 16)
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+
+			switch {
+			case n == discoveryPacketSize && binary.BigEndian.Uint16(buf) == 0x1:
+				response := make([]byte, discoveryPacketSize)
+				binary.BigEndian.PutUint16(response, 0x2)
+				binary.BigEndian.PutUint16(response[2:], discoveryPacketSize-4)
+				copy(response[4:], buf[4:8])
+				copy(response[8:], addr.IP.String())
+				binary.BigEndian.PutUint16(response[discoveryPacketSize-2:], uint16(addr.Port))
+				conn.WriteToUDP(response, addr)
+
+			case n > rtpHeaderSize && buf[1] == rtpPayloadType:
+				packet, err := c.decrypt(buf[:n])
+				if err != nil {
+					t.Error(err)
+					continue
+				}
+				received <- packet
+				conn.WriteToUDP(buf[:n], addr)
+			}
+		}
+	}()
+
+	return conn, received
+}
+
+func TestUDPConn(t *testing.T) {
+	server, decrypted := newTestVoiceServer(t, &crypter{mode: ModeXSalsa20Lite})
+	defer server.Close()
+	port := server.LocalAddr().(*net.UDPAddr).Port
+
+	gateway, received := newTestGateway(t, Ready{SSRC: 10, IP: "127.0.0.1", Port: port, Modes: []string{"unsupported", ModeXSalsa20Lite}})
+	defer gateway.Close()
+
+	c, err := Dial("ws"+strings.TrimPrefix(gateway.URL, "http"), Identify{ServerID: "1", Token: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	u, err := c.OpenUDP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer u.Close()
+
+	opus := []byte("an opus frame")
+	u.OpusSend <- opus
+
+	select {
+	case p := <-received:
+		if p.OP != OPCodeSpeaking {
+			t.Errorf("expected a speaking update, received op %d", p.OP)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no speaking update sent")
+	}
+
+	select {
+	case packet := <-decrypted:
+		if packet.SSRC != 10 || !bytes.Equal(packet.Opus, opus) {
+			t.Errorf("unexpected packet sent: %#v", packet)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no packet sent")
+	}
+
+	select {
+	case packet := <-u.OpusRecv:
+		if !bytes.Equal(packet.Opus, opus) {
+			t.Errorf("unexpected packet received: %#v", packet)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no packet received")
+	}
+}
@@ -0,0 +1,308 @@
+// Package voice implements the Discord voice gateway. Use gocord's Shard.JoinVoiceChannel to obtain the
+// session and server used to connect
+package voice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Voice OPCodes, as documented at https://discordapp.com/developers/docs/topics/opcodes-and-status-codes#voice
+const (
+	OPCodeIdentify = iota
+	OPCodeSelectProtocol
+	OPCodeReady
+	OPCodeHeartbeat
+	OPCodeSessionDescription
+	OPCodeSpeaking
+	OPCodeHeartbeatAck
+	OPCodeResume
+	OPCodeHello
+	OPCodeResumed
+	_
+	_
+	_
+	OPCodeClientDisconnect
+)
+
+const (
+	// Version is the voice gateway version
+	Version = 4
+	// handshakeTimeout is the time to wait for every step of the handshake
+	handshakeTimeout = 10 * time.Second
+)
+
+var (
+	// ErrHandshakeTimeout is returned when the voice gateway doesn't answer a step of the handshake in time
+	ErrHandshakeTimeout = errors.New("voice: timed out during the handshake")
+	// ErrClosed is returned when using a closed connection
+	ErrClosed = errors.New("voice: connection closed")
+)
+
+// Identify is sent to start a voice session, using the session ID and token sent in the gateway's
+// VOICE_STATE_UPDATE and VOICE_SERVER_UPDATE events
+type Identify struct {
+	ServerID  string `json:"server_id"` // the guild ID
+	UserID    string `json:"user_id"`
+	SessionID string `json:"session_id"`
+	Token     string `json:"token"`
+}
+
+// Ready is sent once identified, with the UDP server to send audio to
+type Ready struct {
+	SSRC  uint32   `json:"ssrc"`
+	IP    string   `json:"ip"`
+	Port  int      `json:"port"`
+	Modes []string `json:"modes"` // the supported encryption modes
+}
+
+// SessionDescription is sent once a protocol is selected, with the key used to encrypt audio
+type SessionDescription struct {
+	Mode      string   `json:"mode"`
+	SecretKey [32]byte `json:"secret_key"`
+}
+
+type hello struct {
+	HeartbeatInterval float64 `json:"heartbeat_interval"`
+}
+
+type selectProtocol struct {
+	Protocol string             `json:"protocol"`
+	Data     selectProtocolData `json:"data"`
+}
+
+type selectProtocolData struct {
+	Address string `json:"address"`
+	Port    int    `json:"port"`
+	Mode    string `json:"mode"`
+}
+
+type payload struct {
+	OP int         `json:"op"`
+	D  interface{} `json:"d"`
+}
+
+type receivePayload struct {
+	OP int             `json:"op"`
+	D  json.RawMessage `json:"d"`
+}
+
+// Conn is a connection to the voice gateway
+type Conn struct {
+	sync.Mutex
+	ws *websocket.Conn
+
+	Ready   Ready
+	Latency time.Duration // heartbeat ack latency
+
+	lastHeartbeatSent int64
+	descriptions      chan *SessionDescription
+	closed            chan struct{}
+	closeOnce         sync.Once
+	err               error // the error the connection closed with
+}
+
+// Dial connects to the voice gateway endpoint sent in VOICE_SERVER_UPDATE, and identifies. Dial returns once
+// the voice gateway is ready, SelectProtocol should then be used to finish the handshake
+func Dial(endpoint string, identify Identify) (*Conn, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "wss://" + endpoint
+	}
+	// the endpoint used to include the port 80, which doesn't work with wss
+	endpoint = strings.TrimSuffix(endpoint, ":80")
+
+	ws, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("%s/?v=%d", endpoint, Version), nil)
+	if err != nil {
+		return nil, fmt.Errorf("voice: failed to connect: %s", err.Error())
+	}
+
+	c := &Conn{
+		ws:           ws,
+		descriptions: make(chan *SessionDescription, 1),
+		closed:       make(chan struct{}),
+	}
+
+	err = c.send(OPCodeIdentify, identify)
+	if err != nil {
+		ws.Close()
+		return nil, err
+	}
+
+	// hello and ready may be received in any order
+	ws.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	var helloReceived, readyReceived bool
+	for !helloReceived || !readyReceived {
+		var p receivePayload
+		err = ws.ReadJSON(&p)
+		if err != nil {
+			ws.Close()
+			return nil, fmt.Errorf("voice: handshake failed: %s", err.Error())
+		}
+
+		switch p.OP {
+		case OPCodeHello:
+			var h hello
+			err = json.Unmarshal(p.D, &h)
+			if err != nil {
+				ws.Close()
+				return nil, err
+			}
+			helloReceived = true
+			go c.heartbeat(time.Duration(h.HeartbeatInterval * float64(time.Millisecond)))
+
+		case OPCodeReady:
+			err = json.Unmarshal(p.D, &c.Ready)
+			if err != nil {
+				ws.Close()
+				return nil, err
+			}
+			readyReceived = true
+		}
+	}
+	ws.SetReadDeadline(time.Time{})
+
+	go c.listen()
+	return c, nil
+}
+
+// SelectProtocol sends the address and port discovered over UDP with the encryption mode to use, and waits
+// for the session description holding the secret key
+func (c *Conn) SelectProtocol(address string, port int, mode string) (*SessionDescription, error) {
+	err := c.send(OPCodeSelectProtocol, selectProtocol{
+		Protocol: "udp",
+		Data: selectProtocolData{
+			Address: address,
+			Port:    port,
+			Mode:    mode,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case description := <-c.descriptions:
+		return description, nil
+	case <-c.closed:
+		return nil, c.err
+	case <-time.After(handshakeTimeout):
+		return nil, ErrHandshakeTimeout
+	}
+}
+
+type speaking struct {
+	Speaking int    `json:"speaking"`
+	Delay    int    `json:"delay"`
+	SSRC     uint32 `json:"ssrc"`
+}
+
+// Speaking sends a speaking update for the SSRC audio is sent with
+func (c *Conn) Speaking(ssrc uint32, isSpeaking bool) error {
+	var flags int
+	if isSpeaking {
+		flags = 1
+	}
+
+	return c.send(OPCodeSpeaking, speaking{
+		Speaking: flags,
+		SSRC:     ssrc,
+	})
+}
+
+// Close closes the connection to the voice gateway
+func (c *Conn) Close() error {
+	c.closeWith(ErrClosed)
+
+	c.Lock()
+	defer c.Unlock()
+	c.ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	return c.ws.Close()
+}
+
+// Done is closed once the connection is closed
+func (c *Conn) Done() <-chan struct{} {
+	return c.closed
+}
+
+// Err returns the error the connection closed with
+func (c *Conn) Err() error {
+	select {
+	case <-c.closed:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+func (c *Conn) closeWith(err error) {
+	c.closeOnce.Do(func() {
+		c.err = err
+		close(c.closed)
+	})
+}
+
+func (c *Conn) send(op int, d interface{}) error {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.ws.WriteJSON(&payload{
+		OP: op,
+		D:  d,
+	})
+}
+
+func (c *Conn) listen() {
+	for {
+		var p receivePayload
+		err := c.ws.ReadJSON(&p)
+		if err != nil {
+			c.closeWith(err)
+			c.ws.Close()
+			return
+		}
+
+		switch p.OP {
+		case OPCodeSessionDescription:
+			var description SessionDescription
+			if json.Unmarshal(p.D, &description) == nil {
+				select {
+				case c.descriptions <- &description:
+				default:
+				}
+			}
+
+		case OPCodeHeartbeatAck:
+			c.Lock()
+			c.Latency = time.Duration(time.Now().UnixNano() - c.lastHeartbeatSent)
+			c.Unlock()
+		}
+	}
+}
+
+func (c *Conn) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			c.Lock()
+			c.lastHeartbeatSent = now.UnixNano()
+			c.Unlock()
+
+			// the nonce sent is echoed back in the ack
+			if c.send(OPCodeHeartbeat, now.UnixNano()/int64(time.Millisecond)) != nil {
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package voice
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestGateway starts a voice gateway stand-in running the handshake, and sends every payload received
+// after it on the returned channel
+func newTestGateway(t *testing.T, ready Ready) (*httptest.Server, <-chan *receivePayload) {
+	received := make(chan *receivePayload, 16)
+	upgrader := websocket.Upgrader{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("v") != "4" {
+			t.Errorf("unexpected voice gateway version %s", r.URL.Query().Get("v"))
+		}
+
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		ws.WriteJSON(payload{OPCodeHello, hello{HeartbeatInterval: 41250.5}})
+		for {
+			var p receivePayload
+			if err := ws.ReadJSON(&p); err != nil {
+				return
+			}
+
+			switch p.OP {
+			case OPCodeIdentify:
+				var identify Identify
+				json.Unmarshal(p.D, &identify)
+				if identify.Token != "token" || identify.ServerID != "1" {
+					ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(4004, "Authentication failed."))
+					return
+				}
+				ws.WriteJSON(payload{OPCodeReady, ready})
+
+			case OPCodeSelectProtocol:
+				var protocol selectProtocol
+				json.Unmarshal(p.D, &protocol)
+				ws.WriteJSON(payload{OPCodeSessionDescription, map[string]interface{}{
+					"mode":       protocol.Data.Mode,
+					"secret_key": make([]int, 32),
+				}})
+
+			default:
+				received <- &p
+			}
+		}
+	}))
+
+	return server, received
+}
+
+func TestHandshake(t *testing.T) {
+	server, _ := newTestGateway(t, Ready{SSRC: 10, IP: "127.0.0.1", Port: 50000, Modes: []string{ModeXSalsa20}})
+	defer server.Close()
+	endpoint := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	t.Run("ready", func(t *testing.T) {
+		c, err := Dial(endpoint, Identify{ServerID: "1", UserID: "2", SessionID: "session", Token: "token"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+
+		if c.Ready.SSRC != 10 || c.Ready.Port != 50000 {
+			t.Errorf("unexpected ready payload: %#v", c.Ready)
+		}
+
+		description, err := c.SelectProtocol("127.0.0.1", 40000, ModeXSalsa20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if description.Mode != ModeXSalsa20 {
+			t.Errorf("unexpected session description: %#v", description)
+		}
+	})
+
+	t.Run("authentication failed", func(t *testing.T) {
+		_, err := Dial(endpoint, Identify{ServerID: "1", Token: "invalid"})
+		if err == nil {
+			t.Error("expected the handshake to fail")
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package gocord
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestJoinVoiceChannel(t *testing.T) {
+	ready := map[string]interface{}{
+		"session_id": "session",
+		"user":       map[string]interface{}{"id": "2"},
+	}
+	// the voice state of the current user is only sent with the voice states intent, which the shard
+	// identifies with even though no handler is subscribed to voice events
+	var intents Intents
+	server := newTestGateway(ready, func(ws *websocket.Conn, payload *receivePayload) {
+		if payload.OP == OPCodeIdentify {
+			var identify identifyPayload
+			json.Unmarshal(payload.D, &identify)
+			intents = identify.Intents
+			return
+		}
+		if payload.OP != OPCodeVoiceStateUpdate || !intents.Has(IntentsGuildVoiceStates) {
+			return
+		}
+
+		var update voiceStateUpdatePayload
+		json.Unmarshal(payload.D, &update)
+		ws.WriteJSON(map[string]interface{}{"op": OPCodeDispatch, "t": VoiceServerUpdateEvent, "d": map[string]interface{}{
+			"guild_id": update.GuildID,
+			"token":    "voice token",
+			"endpoint": "voice.discord.gg",
+		}})
+		// another user's voice state is ignored
+		ws.WriteJSON(map[string]interface{}{"op": OPCodeDispatch, "t": VoiceStateUpdateEvent, "d": map[string]interface{}{
+			"guild_id":   update.GuildID,
+			"channel_id": *update.ChannelID,
+			"user_id":    "3",
+			"session_id": "another session",
+		}})
+		ws.WriteJSON(map[string]interface{}{"op": OPCodeDispatch, "t": VoiceStateUpdateEvent, "d": map[string]interface{}{
+			"guild_id":   update.GuildID,
+			"channel_id": *update.ChannelID,
+			"user_id":    "2",
+			"session_id": "voice session",
+		}})
+	})
+	defer server.Close()
+
+	shard := connectTestShard(t, server)
+	defer shard.Close()
+
+	connection, err := shard.JoinVoiceChannel("1", "4", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := VoiceConnection{
+		GuildID:   "1",
+		ChannelID: "4",
+		UserID:    "2",
+		SessionID: "voice session",
+		Token:     "voice token",
+		Endpoint:  "voice.discord.gg",
+	}
+	if *connection != expected {
+		t.Errorf("expected %#v, received %#v", expected, *connection)
+	}
+}
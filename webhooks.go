@@ -0,0 +1,230 @@
+package gocord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Soumil07/gocord/embeds"
+	"github.com/Soumil07/gocord/rest"
+)
+
+// contains webhook related structs and methods
+
+// Webhook types
+const (
+	WebhookTypeIncoming        = 1
+	WebhookTypeChannelFollower = 2
+)
+
+// Webhook represents a webhook of a channel
+type Webhook struct {
+	ID        string `json:"id"`
+	Type      int    `json:"type"`
+	GuildID   string `json:"guild_id,omitempty"`
+	ChannelID string `json:"channel_id"`
+	User      *User  `json:"user,omitempty"` // the user who created the webhook
+	Name      string `json:"name"`
+	Avatar    string `json:"avatar,omitempty"`
+	Token     string `json:"token,omitempty"` // incoming webhooks only
+}
+
+// ModifyWebhook are the settings of a webhook to modify, empty fields are left unchanged
+type ModifyWebhook struct {
+	Name      string `json:"name,omitempty"`
+	Avatar    string `json:"avatar,omitempty"`     // a data URI such as data:image/png;base64,...
+	ChannelID string `json:"channel_id,omitempty"` // moves the webhook to another channel
+}
+
+// WebhookMessage is a message sent by executing a webhook
+type WebhookMessage struct {
+	Content   string          `json:"content,omitempty"`
+	Username  string          `json:"username,omitempty"`   // overrides the name of the webhook
+	AvatarURL string          `json:"avatar_url,omitempty"` // overrides the avatar of the webhook
+	TTS       bool            `json:"tts,omitempty"`
+	Embeds    []*embeds.Embed `json:"embeds,omitempty"`
+	Files     []rest.File     `json:"-"`
+}
+
+// EditWebhookMessage are the changes to a message sent by a webhook, nil fields are left unchanged
+type EditWebhookMessage struct {
+	Content *string         `json:"content,omitempty"`
+	Embeds  []*embeds.Embed `json:"embeds,omitempty"`
+	Files   []rest.File     `json:"-"` // files attached to the message
+}
+
+// CreateWebhook creates a webhook in a channel. The avatar is a data URI such as data:image/png;base64,...
+// and may be empty. Pass rest.WithReason to record a reason in the audit log
+func (c *Cluster) CreateWebhook(channelID, name, avatar string, options ...rest.RequestOption) (*Webhook, error) {
+	return c.CreateWebhookContext(context.Background(), channelID, name, avatar, options...)
+}
+
+// CreateWebhookContext is CreateWebhook with a context
+func (c *Cluster) CreateWebhookContext(ctx context.Context, channelID, name, avatar string, options ...rest.RequestOption) (w *Webhook, err error) {
+	endpoint := rest.ChannelWebhooks(channelID)
+
+	body, err := json.Marshal(&struct {
+		Name   string `json:"name"`
+		Avatar string `json:"avatar,omitempty"`
+	}{name, avatar})
+	if err != nil {
+		return
+	}
+
+	err = c.Rest.DoWithOptions(ctx, http.MethodPost, endpoint, body, &w, options...)
+	return
+}
+
+// FetchChannelWebhooks fetches the webhooks of a channel
+func (c *Cluster) FetchChannelWebhooks(channelID string) ([]*Webhook, error) {
+	return c.FetchChannelWebhooksContext(context.Background(), channelID)
+}
+
+// FetchChannelWebhooksContext is FetchChannelWebhooks with a context
+func (c *Cluster) FetchChannelWebhooksContext(ctx context.Context, channelID string) (webhooks []*Webhook, err error) {
+	endpoint := rest.ChannelWebhooks(channelID)
+	err = c.Rest.DoContext(ctx, http.MethodGet, endpoint, nil, &webhooks)
+	return
+}
+
+// FetchGuildWebhooks fetches the webhooks of every channel of a guild
+func (c *Cluster) FetchGuildWebhooks(guildID string) ([]*Webhook, error) {
+	return c.FetchGuildWebhooksContext(context.Background(), guildID)
+}
+
+// FetchGuildWebhooksContext is FetchGuildWebhooks with a context
+func (c *Cluster) FetchGuildWebhooksContext(ctx context.Context, guildID string) (webhooks []*Webhook, err error) {
+	endpoint := rest.GuildWebhooks(guildID)
+	err = c.Rest.DoContext(ctx, http.MethodGet, endpoint, nil, &webhooks)
+	return
+}
+
+// FetchWebhook fetches a webhook given an ID
+func (c *Cluster) FetchWebhook(webhookID string) (*Webhook, error) {
+	return c.FetchWebhookContext(context.Background(), webhookID)
+}
+
+// FetchWebhookContext is FetchWebhook with a context
+func (c *Cluster) FetchWebhookContext(ctx context.Context, webhookID string) (w *Webhook, err error) {
+	endpoint := rest.Webhook(webhookID)
+	err = c.Rest.DoContext(ctx, http.MethodGet, endpoint, nil, &w)
+	return
+}
+
+// ModifyWebhook updates the settings of a webhook. Pass rest.WithReason to record a reason in the audit log
+func (c *Cluster) ModifyWebhook(webhookID string, data ModifyWebhook, options ...rest.RequestOption) (*Webhook, error) {
+	return c.ModifyWebhookContext(context.Background(), webhookID, data, options...)
+}
+
+// ModifyWebhookContext is ModifyWebhook with a context
+func (c *Cluster) ModifyWebhookContext(ctx context.Context, webhookID string, data ModifyWebhook, options ...rest.RequestOption) (w *Webhook, err error) {
+	endpoint := rest.Webhook(webhookID)
+
+	body, err := json.Marshal(&data)
+	if err != nil {
+		return
+	}
+
+	err = c.Rest.DoWithOptions(ctx, http.MethodPatch, endpoint, body, &w, options...)
+	return
+}
+
+// DeleteWebhook deletes a webhook. Pass rest.WithReason to record a reason in the audit log
+func (c *Cluster) DeleteWebhook(webhookID string, options ...rest.RequestOption) error {
+	return c.DeleteWebhookContext(context.Background(), webhookID, options...)
+}
+
+// DeleteWebhookContext is DeleteWebhook with a context
+func (c *Cluster) DeleteWebhookContext(ctx context.Context, webhookID string, options ...rest.RequestOption) (err error) {
+	endpoint := rest.Webhook(webhookID)
+	err = c.Rest.DoWithOptions(ctx, http.MethodDelete, endpoint, nil, nil, options...)
+	return
+}
+
+// WebhookClient executes a webhook with its token, without a bot token or a cluster
+type WebhookClient struct {
+	ID    string
+	Token string
+	// Rest sends the requests of the client. It can be replaced by the manager of a cluster for the
+	// client to share its rate limits
+	Rest *rest.RestManager
+}
+
+// NewWebhookClient returns a client executing the webhook with the ID and token, its rest manager being
+// configured by the options
+func NewWebhookClient(webhookID, token string, options ...rest.Option) *WebhookClient {
+	return &WebhookClient{
+		ID:    webhookID,
+		Token: token,
+		Rest:  rest.NewRestManager("", options...),
+	}
+}
+
+// NewWebhookClientURL returns a client executing the webhook of a URL such as
+// https://discord.com/api/webhooks/id/token, its rest manager being configured by the options
+func NewWebhookClientURL(webhookURL string, options ...rest.Option) (*WebhookClient, error) {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	for i, part := range parts {
+		if part == "webhooks" && i+2 < len(parts) && parts[i+1] != "" && parts[i+2] != "" {
+			return NewWebhookClient(parts[i+1], parts[i+2], options...), nil
+		}
+	}
+
+	return nil, errors.New("the URL isn't a webhook URL")
+}
+
+// Execute sends a message with the webhook, and returns it once it's created
+func (w *WebhookClient) Execute(message WebhookMessage) (*Message, error) {
+	return w.ExecuteContext(context.Background(), message)
+}
+
+// ExecuteContext is Execute with a context
+func (w *WebhookClient) ExecuteContext(ctx context.Context, message WebhookMessage) (m *Message, err error) {
+	endpoint := rest.WebhookToken(w.ID, w.Token) + "?wait=true"
+
+	body, err := json.Marshal(&message)
+	if err != nil {
+		return
+	}
+
+	err = w.Rest.DoContext(ctx, http.MethodPost, endpoint, body, &m, message.Files...)
+	return
+}
+
+// EditMessage edits a message sent by the webhook
+func (w *WebhookClient) EditMessage(messageID string, data EditWebhookMessage) (*Message, error) {
+	return w.EditMessageContext(context.Background(), messageID, data)
+}
+
+// EditMessageContext is EditMessage with a context
+func (w *WebhookClient) EditMessageContext(ctx context.Context, messageID string, data EditWebhookMessage) (m *Message, err error) {
+	endpoint := rest.WebhookMessage(w.ID, w.Token, messageID)
+
+	body, err := json.Marshal(&data)
+	if err != nil {
+		return
+	}
+
+	err = w.Rest.DoContext(ctx, http.MethodPatch, endpoint, body, &m, data.Files...)
+	return
+}
+
+// DeleteMessage deletes a message sent by the webhook
+func (w *WebhookClient) DeleteMessage(messageID string) error {
+	return w.DeleteMessageContext(context.Background(), messageID)
+}
+
+// DeleteMessageContext is DeleteMessage with a context
+func (w *WebhookClient) DeleteMessageContext(ctx context.Context, messageID string) (err error) {
+	endpoint := rest.WebhookMessage(w.ID, w.Token, messageID)
+	err = w.Rest.DoContext(ctx, http.MethodDelete, endpoint, nil, nil)
+	return
+}
@@ -0,0 +1,199 @@
+package gocord
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Soumil07/gocord/embeds"
+	"github.com/Soumil07/gocord/rest"
+)
+
+func TestWebhookEndpoints(t *testing.T) {
+	c, requests := newTestAPI(t, nil)
+
+	tests := []struct {
+		name   string
+		call   func() error
+		method string
+		path   string
+		body   string
+		reason string
+	}{
+		{
+			name:   "create",
+			call:   func() error { _, err := c.CreateWebhook("1", "logs", "", rest.WithReason("logging")); return err },
+			method: http.MethodPost,
+			path:   "/v6/channels/1/webhooks",
+			body:   `{"name":"logs"}`,
+			reason: "logging",
+		},
+		{
+			name:   "fetch channel webhooks",
+			call:   func() error { _, err := c.FetchChannelWebhooks("1"); return err },
+			method: http.MethodGet,
+			path:   "/v6/channels/1/webhooks",
+		},
+		{
+			name:   "fetch guild webhooks",
+			call:   func() error { _, err := c.FetchGuildWebhooks("2"); return err },
+			method: http.MethodGet,
+			path:   "/v6/guilds/2/webhooks",
+		},
+		{
+			name:   "fetch",
+			call:   func() error { _, err := c.FetchWebhook("3"); return err },
+			method: http.MethodGet,
+			path:   "/v6/webhooks/3",
+		},
+		{
+			name:   "modify",
+			call:   func() error { _, err := c.ModifyWebhook("3", ModifyWebhook{ChannelID: "4"}); return err },
+			method: http.MethodPatch,
+			path:   "/v6/webhooks/3",
+			body:   `{"channel_id":"4"}`,
+		},
+		{
+			name:   "delete",
+			call:   func() error { return c.DeleteWebhook("3", rest.WithReason("unused")) },
+			method: http.MethodDelete,
+			path:   "/v6/webhooks/3",
+			reason: "unused",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.call(); err != nil {
+				t.Fatal(err)
+			}
+
+			req := (*requests)[len(*requests)-1]
+			if req.Method != test.method || req.URL.Path != test.path {
+				t.Errorf("unexpected request: %s %s", req.Method, req.URL.Path)
+			}
+			if req.Body != test.body {
+				t.Errorf("unexpected body: %s", req.Body)
+			}
+			if reason := req.Header.Get("X-Audit-Log-Reason"); reason != test.reason {
+				t.Errorf("unexpected reason: %q", reason)
+			}
+		})
+	}
+}
+
+func TestWebhookClient(t *testing.T) {
+	var requests []apiRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []byte
+		if file, _, err := r.FormFile("files[0]"); err == nil {
+			body, _ = ioutil.ReadAll(file)
+			body = append([]byte(r.FormValue("payload_json")+" "), body...)
+		} else {
+			body, _ = ioutil.ReadAll(r.Body)
+		}
+		requests = append(requests, apiRequest{r, string(body)})
+
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`{"id": "5", "channel_id": "1", "content": "hello"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewWebhookClientURL("https://discord.com/api/webhooks/3/secret-token", rest.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("execute", func(t *testing.T) {
+		m, err := client.Execute(WebhookMessage{
+			Content:  "hello",
+			Username: "logger",
+			Embeds:   []*embeds.Embed{embeds.New().SetTitle("deploy")},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.ID != "5" {
+			t.Errorf("unexpected message: %+v", m)
+		}
+
+		req := requests[len(requests)-1]
+		if req.Method != http.MethodPost || req.URL.Path != "/v6/webhooks/3/secret-token" || req.URL.RawQuery != "wait=true" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+		}
+		if !strings.HasPrefix(req.Body, `{"content":"hello","username":"logger","embeds":[{"title":"deploy"`) {
+			t.Errorf("unexpected body: %s", req.Body)
+		}
+		if auth := req.Header.Get("Authorization"); auth != "" {
+			t.Errorf("a token was sent: %s", auth)
+		}
+	})
+
+	t.Run("execute with files", func(t *testing.T) {
+		_, err := client.Execute(WebhookMessage{Files: []rest.File{{Name: "log.txt", Reader: strings.NewReader("line")}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req := requests[len(requests)-1]
+		if req.Body != `{"attachments":[{"id":0,"filename":"log.txt"}]} line` {
+			t.Errorf("unexpected body: %s", req.Body)
+		}
+	})
+
+	t.Run("edit", func(t *testing.T) {
+		content := "edited"
+		if _, err := client.EditMessage("5", EditWebhookMessage{Content: &content}); err != nil {
+			t.Fatal(err)
+		}
+
+		req := requests[len(requests)-1]
+		if req.Method != http.MethodPatch || req.URL.Path != "/v6/webhooks/3/secret-token/messages/5" || req.Body != `{"content":"edited"}` {
+			t.Errorf("unexpected request: %s %s %s", req.Method, req.URL.Path, req.Body)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := client.DeleteMessage("5"); err != nil {
+			t.Fatal(err)
+		}
+
+		req := requests[len(requests)-1]
+		if req.Method != http.MethodDelete || req.URL.Path != "/v6/webhooks/3/secret-token/messages/5" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL.Path)
+		}
+	})
+}
+
+func TestNewWebhookClientURL(t *testing.T) {
+	tests := []struct {
+		url, id, token string
+	}{
+		{"https://discord.com/api/webhooks/1/token", "1", "token"},
+		{"https://discordapp.com/api/v8/webhooks/1/token/", "1", "token"},
+		{"https://discord.com/api/webhooks/1", "", ""},
+		{"https://discord.com/api/channels/1/messages", "", ""},
+	}
+
+	for _, test := range tests {
+		client, err := NewWebhookClientURL(test.url)
+		if test.id == "" {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.url)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if client.ID != test.id || client.Token != test.token {
+			t.Errorf("%s: unexpected webhook %s with token %s", test.url, client.ID, client.Token)
+		}
+	}
+}